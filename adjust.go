@@ -0,0 +1,89 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Adjustments tweak the image after it is resized and before it is mapped
+// to brick colors. Zero values leave the image unchanged.
+type Adjustments struct {
+	Brightness float64 // Added to every channel, from -1 to 1.
+	Contrast   float64 // From -1 (flat grey) up; 1 doubles the contrast.
+	Saturation float64 // From -1 (greyscale) up; 1 doubles the saturation.
+	Gamma      float64 // Gamma correction; values above 1 brighten midtones.
+	Sharpen    float64 // Amount of unsharp masking; around 1 is strong.
+}
+
+func (a *Adjustments) identity() bool {
+	return *a == Adjustments{} || *a == Adjustments{Gamma: 1}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// sharpen applies a 3x3 unsharp mask to the channels of img.
+func sharpen(pix [][3]float64, w, h int, amount float64) [][3]float64 {
+	out := make([][3]float64, len(pix))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var blur [3]float64
+			n := 0.0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					xx, yy := x+dx, y+dy
+					if xx < 0 || yy < 0 || xx >= w || yy >= h {
+						continue
+					}
+					for c := range blur {
+						blur[c] += pix[yy*w+xx][c]
+					}
+					n++
+				}
+			}
+			for c := range blur {
+				v := pix[y*w+x][c]
+				out[y*w+x][c] = clamp01(v + amount*(v-blur[c]/n))
+			}
+		}
+	}
+	return out
+}
+
+// adjust returns a copy of img with the adjustments applied.
+func (a *Adjustments) adjust(img image.Image) image.Image {
+	if a.identity() {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewNRGBA(image.Rectangle{image.ZP, b.Size()})
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	pix := make([][3]float64, w*h)
+	for i := range pix {
+		for c := range pix[i] {
+			pix[i][c] = float64(src.Pix[4*i+c]) / 255
+		}
+	}
+	if a.Sharpen != 0 {
+		pix = sharpen(pix, w, h, a.Sharpen)
+	}
+	gamma := a.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	for i, p := range pix {
+		for c, v := range p {
+			v = math.Pow(v, 1/gamma) + a.Brightness
+			p[c] = (v-0.5)*(1+a.Contrast) + 0.5
+		}
+		lum := 0.299*p[0] + 0.587*p[1] + 0.114*p[2]
+		for c, v := range p {
+			src.Pix[4*i+c] = uint8(clamp01(lum+(v-lum)*(1+a.Saturation))*255 + 0.5)
+		}
+	}
+	return src
+}
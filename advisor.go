@@ -0,0 +1,78 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// WidthReport describes the panel built at one of the widths tried by
+// SuggestWidths.
+type WidthReport struct {
+	Width, Height uint
+	// ColorError is the mean CIE76 color difference from the image, and
+	// Detail the structural similarity (SSIM, up to 1) with it. Both are
+	// measured at the largest width tried, so they are comparable across
+	// widths.
+	ColorError float64
+	Detail     float64
+	Bricks     int
+	Cost       float64 // Using ESTIMATED_PRICES.
+	Err        error   // If the panel couldn't be built; other fields are zero.
+}
+
+// SuggestWidths builds the panel at every candidate width, keeping the
+// aspect ratio of opt, and reports how well each reproduces img so the
+// smallest one that still looks good can be picked.
+func SuggestWidths(img image.Image, opt *Options, candidates []uint) []WidthReport {
+	var max uint
+	for _, w := range candidates {
+		if w > max {
+			max = w
+		}
+	}
+	result := make([]WidthReport, len(candidates))
+	var reference image.Image
+	for i, w := range candidates {
+		p, err := NewPanel(img, scaledOptions(opt, w))
+		if err == nil && reference == nil {
+			reference, err = target(img, scaledOptions(opt, max))
+		}
+		if err != nil {
+			result[i] = WidthReport{Width: w, Err: err}
+			continue
+		}
+		size := p.Size()
+		seen := scaleNearest(p.studColors(), reference.Bounds().Size())
+		report := WidthReport{Width: uint(size.X), Height: uint(size.Y), Cost: p.Cost(ESTIMATED_PRICES)}
+		report.ColorError, _ = colorError(seen, reference)
+		report.Detail = ssim(seen, reference)
+		for _, n := range p.CountBricks() {
+			report.Bricks += n
+		}
+		result[i] = report
+	}
+	return result
+}
+
+// scaledOptions returns a copy of opt for a panel of the given width,
+// scaling Height along if both were set.
+func scaledOptions(opt *Options, width uint) *Options {
+	o := *opt
+	if opt.Width > 0 && opt.Height > 0 {
+		o.Height = (opt.Height*width + opt.Width/2) / opt.Width
+	} else {
+		o.Height = 0
+	}
+	o.Width = width
+	return &o
+}
+
+// target returns img as the panel built with opt tries to reproduce it,
+// before mapping to brick colors.
+func target(img image.Image, opt *Options) (image.Image, error) {
+	r, err := sourceRect(img, opt)
+	if err != nil {
+		return nil, err
+	}
+	return newGeometry(r, opt).apply(img, opt), nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// AnimationOptions configures ExportBuildAnimation.
+type AnimationOptions struct {
+	Scale   int  // Pixels per stud; defaults to 8.
+	Outline bool // Outline bricks, as in Draw.
+	// BricksPerFrame is the number of bricks added in each frame. If zero,
+	// each frame adds a whole row of bricks.
+	BricksPerFrame int
+	Delay          int // Delay between frames, in 100ths of a second; defaults to 10.
+	FinalDelay     int // Delay of the finished panel; defaults to 300.
+	// TagOrder, if set, adds the bricks with each of these tags in turn,
+	// such as FRAME_TAG first, before the rest; a frame never mixes them.
+	TagOrder []string
+}
+
+// steps groups the bricks, in row-major order, into the animation frames,
+// the bricks with each tag of order first.
+func (p *Panel) steps(perFrame int, order []string) [][]image.Point {
+	rank := make(map[string]int)
+	for i := len(order) - 1; i >= 0; i-- {
+		rank[order[i]] = i
+	}
+	// group returns the index in order of the first tag of the brick at
+	// pos, or len(order) for the rest.
+	group := func(pos image.Point) int {
+		g := len(order)
+		for _, t := range p.tags[pos] {
+			if i, ok := rank[t]; ok && i < g {
+				g = i
+			}
+		}
+		return g
+	}
+	positions := p.positions()
+	var result [][]image.Point
+	for g := 0; g <= len(order); g++ {
+		start := len(result)
+		for _, pos := range positions {
+			if group(pos) != g {
+				continue
+			}
+			n := len(result)
+			switch {
+			case n == start:
+			case perFrame > 0 && len(result[n-1]) < perFrame:
+				result[n-1] = append(result[n-1], pos)
+				continue
+			case perFrame <= 0 && result[n-1][0].Y == pos.Y:
+				result[n-1] = append(result[n-1], pos)
+				continue
+			}
+			result = append(result, []image.Point{pos})
+		}
+	}
+	return result
+}
+
+// ExportBuildAnimation writes an animated GIF showing the panel being
+// assembled, brick by brick in row-major order, starting from a blank frame.
+// Overlay plates are added with the last bricks.
+func (p *Panel) ExportBuildAnimation(w io.Writer, opts AnimationOptions) error {
+	scale, delay, finalDelay := opts.Scale, opts.Delay, opts.FinalDelay
+	if scale <= 0 {
+		scale = 8
+	}
+	if delay <= 0 {
+		delay = 10
+	}
+	if finalDelay <= 0 {
+		finalDelay = 300
+	}
+	palette := color.Palette{color.NRGBA{255, 255, 255, 255}, color.NRGBA{0, 0, 0, 255}}
+	seen := make(map[color.Color]bool)
+	addColor := func(c color.Color) {
+		if !seen[c] {
+			seen[c] = true
+			palette = append(palette, c)
+		}
+	}
+	p.bricks.each(func(_ image.Point, brick *Brick) {
+		addColor(brick.Color.color)
+	})
+	// Overlay plates show as their color blended over the brick below.
+	cells := p.cells()
+	for _, pt := range p.overlayPositions() {
+		if below, ok := colorAt(p, cells, pt); ok {
+			blend := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			blend.Set(0, 0, below.color)
+			draw.Draw(blend, blend.Bounds(), &image.Uniform{p.overlay[pt].color}, image.ZP, draw.Over)
+			addColor(blend.At(0, 0))
+		}
+	}
+
+	var s seam
+	if opts.Outline {
+		s = newSeam(scale, 0, nil, nil)
+	}
+	bounds := image.Rectangle{image.ZP, p.bounds.Size().Mul(scale)}
+	// The first frame is blank; the bricks are drawn on canvas, and every
+	// other frame only holds the area that changed, earlier frames being
+	// kept on screen.
+	canvas := image.NewPaletted(bounds, palette)
+	anim := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(bounds, palette)},
+		Delay: []int{delay},
+	}
+	steps := p.steps(opts.BricksPerFrame, opts.TagOrder)
+	for i, step := range steps {
+		var changed image.Rectangle
+		for _, pos := range step {
+			changed = changed.Union(drawBrick(canvas, pos, p.bricks.at(pos), scale, s))
+		}
+		if i == len(steps)-1 && len(p.overlay) > 0 {
+			p.drawOverlay(canvas, scale)
+			changed = bounds
+		}
+		frame := image.NewPaletted(changed, palette)
+		draw.Draw(frame, changed, canvas, changed.Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	anim.Delay[len(anim.Delay)-1] = finalDelay
+	return gif.EncodeAll(w, anim)
+}
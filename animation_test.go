@@ -0,0 +1,58 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+)
+
+func TestExportBuildAnimation(t *testing.T) {
+	p, err := NewPanel(gradient(96, 64), &Options{
+		Width:    24,
+		Bricks:   BASIC_BRICKS,
+		Overlays: TRANS_COLORS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Overlay()) == 0 {
+		t.Fatal("no overlay plates to animate")
+	}
+	const scale = 4
+	var buf bytes.Buffer
+	if err := p.ExportBuildAnimation(&buf, AnimationOptions{Scale: scale, BricksPerFrame: 10}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (len(p.positions())+9)/10 + 1; len(anim.Image) != want {
+		t.Errorf("%d frames, want %d", len(anim.Image), want)
+	}
+	first := anim.Image[0]
+	for y := first.Rect.Min.Y; y < first.Rect.Max.Y; y++ {
+		for x := first.Rect.Min.X; x < first.Rect.Max.X; x++ {
+			if !near(first.At(x, y), color.White, 0) {
+				t.Fatalf("first frame has %v at (%d, %d), want a blank frame", first.At(x, y), x, y)
+			}
+		}
+	}
+	// Later frames only hold what changed and are drawn over the others.
+	composite := image.NewNRGBA(first.Rect)
+	for _, frame := range anim.Image {
+		draw.Draw(composite, frame.Rect, frame, frame.Rect.Min, draw.Over)
+	}
+	want := p.DrawWith(&DrawOptions{Scale: scale})
+	for y := 0; y < want.Bounds().Dy(); y++ {
+		for x := 0; x < want.Bounds().Dx(); x++ {
+			if !near(composite.At(x, y), want.At(x, y), 1) {
+				t.Fatalf("last frame has %v at (%d, %d), want %v", composite.At(x, y), x, y, want.At(x, y))
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+)
+
+// RenderANSI prints the panel to a terminal that supports 24-bit color, as
+// a quick preview: every character shows two studs, one above the other,
+// as a half block in the color of the top stud over the color of the
+// bottom one. Studs without a brick keep the terminal's colors. Overlay
+// plates are left out.
+func (p *Panel) RenderANSI(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	out := &errWriter{w: buf}
+	cells := p.cells()
+	// at returns the color of the stud at (x, y), or nil.
+	at := func(x, y int) color.Color {
+		if c, ok := colorAt(p, cells, image.Point{x, y}); ok {
+			return c.color
+		}
+		return nil
+	}
+	rgb := func(c color.Color) (uint8, uint8, uint8) {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		return n.R, n.G, n.B
+	}
+	for y := p.bounds.Min.Y; y < p.bounds.Max.Y; y += 2 {
+		for x := p.bounds.Min.X; x < p.bounds.Max.X; x++ {
+			top, bottom := at(x, y), at(x, y+1)
+			switch {
+			case top != nil && bottom != nil:
+				r, g, b := rgb(top)
+				out.printf("\x1b[38;2;%d;%d;%dm", r, g, b)
+				r, g, b = rgb(bottom)
+				out.printf("\x1b[48;2;%d;%d;%dm▀", r, g, b)
+			case top != nil:
+				r, g, b := rgb(top)
+				out.printf("\x1b[49;38;2;%d;%d;%dm▀", r, g, b)
+			case bottom != nil:
+				r, g, b := rgb(bottom)
+				out.printf("\x1b[49;38;2;%d;%d;%dm▄", r, g, b)
+			default:
+				out.printf("\x1b[0m ")
+			}
+		}
+		out.printf("\x1b[0m\n")
+	}
+	if out.err != nil {
+		return out.err
+	}
+	return buf.Flush()
+}
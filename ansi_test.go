@@ -0,0 +1,41 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestRenderANSI(t *testing.T) {
+	p := newPanel(image.Rect(0, 0, 3, 3))
+	for _, b := range []struct {
+		pos   image.Point
+		brick Brick
+	}{
+		{image.Point{0, 0}, Brick{image.Point{1, 1}, WHITE, 0, false}},
+		{image.Point{1, 0}, Brick{image.Point{1, 2}, BRIGHT_RED, 0, false}},
+		{image.Point{2, 1}, Brick{image.Point{1, 1}, BRIGHT_BLUE, 0, false}},
+		{image.Point{0, 2}, Brick{image.Point{1, 1}, WHITE, 0, false}},
+	} {
+		if err := p.Place(b.pos, b.brick); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := p.RenderANSI(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// The odd row at the bottom is drawn over the terminal's background.
+	want := "\x1b[49;38;2;242;243;242m▀" +
+		"\x1b[38;2;196;40;27m\x1b[48;2;196;40;27m▀" +
+		"\x1b[49;38;2;13;105;171m▄" +
+		"\x1b[0m\n" +
+		"\x1b[49;38;2;242;243;242m▀" +
+		"\x1b[0m " +
+		"\x1b[0m " +
+		"\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("RenderANSI printed\n%q\nwant\n%q", got, want)
+	}
+}
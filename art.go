@@ -0,0 +1,127 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+var (
+	// ART_SIZE is the side of a LEGO Art canvas, in studs.
+	ART_SIZE = 48
+	// ART_SECTION is the side of the baseplates a LEGO Art canvas is built
+	// on, and of the sections of its instructions.
+	ART_SECTION = 16
+)
+
+// artPart is the design number of the 1x1 round plate LEGO Art panels use.
+const artPart = overlayPart
+
+// artBricks returns one 1x1 brick for every color of bricks.
+func artBricks(bricks []*Brick) []*Brick {
+	var result []*Brick
+	seen := make(map[Color]bool)
+	for _, brick := range bricks {
+		if !seen[brick.Color] {
+			seen[brick.Color] = true
+			result = append(result, &Brick{image.Point{1, 1}, brick.Color, 0, false})
+		}
+	}
+	return result
+}
+
+// NewArtPanel builds a panel like those of the LEGO Art sets: ART_SIZE
+// studs square, covered with 1x1 round plates in the colors of opt.Bricks.
+// Width, Height, Frame and Overlays are ignored, and Fit, unless set, is
+// Cover rather than Stretch, its zero value, so images that aren't square
+// are cropped instead of distorted; set it to Pad to keep all of the image.
+// The panel is drawn as dots, its exports and bill of materials list round
+// plates, and WriteSections writes instructions section by section.
+func NewArtPanel(img image.Image, opt *Options) (*Panel, error) {
+	if err := validateBricks(opt.Bricks); err != nil {
+		return nil, err
+	}
+	o := *opt
+	o.Width, o.Height = uint(ART_SIZE), uint(ART_SIZE)
+	o.Bricks = artBricks(opt.Bricks)
+	o.Frame, o.Overlays = FrameSpec{}, nil
+	if o.Fit == Stretch {
+		o.Fit = Cover
+	}
+	p, err := NewPanel(img, &o)
+	if err != nil {
+		return nil, err
+	}
+	p.round = true
+	return p, nil
+}
+
+// drawDots renders a panel of round plates as colored dots on a black
+// baseplate.
+func (p *Panel) drawDots(scale int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rectangle{image.ZP, p.bounds.Size().Mul(scale)})
+	draw.Draw(out, out.Bounds(), &image.Uniform{BLACK.color}, image.ZP, draw.Src)
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		dot := &circle{pos.Mul(scale).Add(image.Point{scale / 2, scale / 2}), scale * 9 / 20}
+		draw.DrawMask(out, dot.Bounds(), &image.Uniform{brick.Color.color}, image.ZP, dot,
+			dot.Bounds().Min, draw.Over)
+		top := &circle{dot.center, dot.r * 2 / 3}
+		draw.DrawMask(out, top.Bounds(), &image.Uniform{shade(brick.Color.color, 1.08)}, image.ZP,
+			top, top.Bounds().Min, draw.Over)
+	})
+	return out
+}
+
+// WriteSections writes building instructions split into sections of
+// ART_SECTION studs square, numbered in row-major order as in the LEGO Art
+// sets. Every section lists the pieces it needs, then each of its rows as
+// runs of the same color.
+func (p *Panel) WriteSections(w io.Writer) error {
+	out := &errWriter{w: w}
+	cells := p.cells()
+	noun := "studs of"
+	if p.round {
+		noun = "x 1x1 round plate"
+	}
+	n := ART_SECTION
+	section := 0
+	for y0 := p.bounds.Min.Y; y0 < p.bounds.Max.Y; y0 += n {
+		for x0 := p.bounds.Min.X; x0 < p.bounds.Max.X; x0 += n {
+			section++
+			r := image.Rect(x0, y0, x0+n, y0+n).Intersect(p.bounds)
+			rel := r.Sub(p.bounds.Min)
+			out.printf("Section %d: columns %d-%d, rows %d-%d\n", section,
+				rel.Min.X+1, rel.Max.X, rel.Min.Y+1, rel.Max.Y)
+			count := make(map[Color]int)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					if c, ok := colorAt(p, cells, image.Point{x, y}); ok {
+						count[c]++
+					}
+				}
+			}
+			var colors []Color
+			for c := range count {
+				colors = append(colors, c)
+			}
+			sort.Slice(colors, func(i, j int) bool { return colors[i].name < colors[j].name })
+			for _, c := range colors {
+				out.printf("  %d %s %s\n", count[c], noun, c.name)
+			}
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				out.printf("  Row %d:", y-p.bounds.Min.Y+1)
+				for i, run := range p.rowRuns(cells, y, r.Min.X, r.Max.X) {
+					if i > 0 {
+						out.printf(",")
+					}
+					out.printf(" %d %s", run.n, run.name())
+				}
+				out.printf("\n")
+			}
+			out.printf("\n")
+		}
+	}
+	return out.err
+}
@@ -0,0 +1,30 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"testing"
+)
+
+func TestNewArtPanelFit(t *testing.T) {
+	img := gradient(300, 100)
+	for _, tc := range []struct {
+		name     string
+		fit, use Fit
+	}{
+		{"default", Stretch, Cover},
+		{"cover", Cover, Cover},
+		{"pad", Pad, Pad},
+	} {
+		got, err := NewArtPanel(img, &Options{Fit: tc.fit, Bricks: ALL_BRICKS})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := NewPanel(img, &Options{Width: uint(ART_SIZE), Height: uint(ART_SIZE), Fit: tc.use, Bricks: artBricks(ALL_BRICKS)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Size() != want.Size() || !samePanels(got, want) {
+			t.Errorf("%s: panel differs from one built with Fit %v", tc.name, tc.use)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// BOMFormat selects the output format of WriteBOM.
+type BOMFormat int
+
+const (
+	CSV  BOMFormat = iota // Comma separated values, with a header row.
+	TSV                   // Tab separated values, with a header row.
+	Text                  // Aligned table meant to be read by people.
+)
+
+// sortBricks orders bricks by color name, then by size, then plain bricks
+// first.
+func sortBricks(bricks []Brick) {
+	sort.Slice(bricks, func(i, j int) bool {
+		a, b := bricks[i], bricks[j]
+		if a.Color.name != b.Color.name {
+			return a.Color.name < b.Color.name
+		}
+		if a.Size.X*a.Size.Y != b.Size.X*b.Size.Y {
+			return a.Size.X*a.Size.Y < b.Size.X*b.Size.Y
+		}
+		if a.Size.X != b.Size.X {
+			return a.Size.X < b.Size.X
+		}
+		if a.Size.Y != b.Size.Y {
+			return a.Size.Y < b.Size.Y
+		}
+		if a.Mask != b.Mask {
+			return a.Mask < b.Mask
+		}
+		return !a.Technic && b.Technic
+	})
+}
+
+// WriteBOM writes the bill of materials of the panel: how many bricks of each
+// color and size are needed.
+func (p *Panel) WriteBOM(w io.Writer, format BOMFormat) error {
+	return p.WritePricedBOM(w, format, nil)
+}
+
+// WritePricedBOM is like WriteBOM, but also lists unit and total prices taken
+// from prices, unless it is nil.
+func (p *Panel) WritePricedBOM(w io.Writer, format BOMFormat, prices PriceList) error {
+	count := p.CountBricks()
+	var bricks []Brick
+	for brick := range count {
+		bricks = append(bricks, brick)
+	}
+	sortBricks(bricks)
+
+	header := []string{"Size", "Color", "Count"}
+	if prices != nil {
+		header = append(header, "Unit price", "Price")
+	}
+	rows := [][]string{header}
+	total, cost := 0, 0.0
+	for _, brick := range bricks {
+		n := count[brick]
+		name := brick.sizeName()
+		if p.round {
+			name = "1x1 round plate"
+		}
+		row := []string{name, brick.Color.name, strconv.Itoa(n)}
+		if prices != nil {
+			price := prices.price(brick, p.round)
+			row = append(row, strconv.FormatFloat(price, 'f', 2, 64),
+				strconv.FormatFloat(price*float64(n), 'f', 2, 64))
+			cost += price * float64(n)
+		}
+		rows = append(rows, row)
+		total += n
+	}
+	overlay := p.CountOverlay()
+	var colors []Color
+	for c := range overlay {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool { return colors[i].name < colors[j].name })
+	for _, c := range colors {
+		n := overlay[c]
+		row := []string{"1x1 round plate", c.name, strconv.Itoa(n)}
+		if prices != nil {
+			price := prices[RoundPlate(c)]
+			row = append(row, strconv.FormatFloat(price, 'f', 2, 64),
+				strconv.FormatFloat(price*float64(n), 'f', 2, 64))
+			cost += price * float64(n)
+		}
+		rows = append(rows, row)
+		total += n
+	}
+
+	switch format {
+	case CSV, TSV:
+		cw := csv.NewWriter(w)
+		if format == TSV {
+			cw.Comma = '\t'
+		}
+		cw.WriteAll(rows)
+		return cw.Error()
+	case Text:
+		footer := []string{"", "Total", strconv.Itoa(total)}
+		if prices != nil {
+			footer = append(footer, "", strconv.FormatFloat(cost, 'f', 2, 64))
+		}
+		rows = append(rows, footer)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, row := range rows {
+			if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("lego: unknown BOM format %d", format)
+}
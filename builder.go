@@ -0,0 +1,253 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"sync"
+)
+
+// MAX_CACHED_COLORS limits how many image colors a Builder remembers the
+// brick color of.
+var MAX_CACHED_COLORS = 1 << 16
+
+// buildCache holds what panels built with the same options share: the
+// palette, the brick color of every palette color, the order bricks are
+// tried in, the lookup table of Options.LUTBits and, if nearest is set, the
+// palette index image colors map to.
+type buildCache struct {
+	palette color.Palette
+	m       map[color.Color]Color
+	ordered map[Color][]Brick
+	lut     *colorLUT
+
+	mu      sync.RWMutex
+	nearest map[color.Color]uint8
+}
+
+func newBuildCache(opt *Options) *buildCache {
+	cache := &buildCache{}
+	cache.palette, cache.m = paletteOf(opt.Bricks)
+	if opt.LUTBits > 0 {
+		cache.lut = newColorLUT(cache.palette, opt.LUTBits)
+	}
+	return cache
+}
+
+// paletteOf returns the colors of bricks, in order, and the brick color of
+// every one.
+func paletteOf(bricks []*Brick) (color.Palette, map[color.Color]Color) {
+	var palette color.Palette
+	m := make(map[color.Color]Color)
+	for _, brick := range bricks {
+		if _, ok := m[brick.Color.color]; !ok {
+			m[brick.Color.color] = brick.Color
+			palette = append(palette, brick.Color.color)
+		}
+	}
+	return palette, m
+}
+
+// draw maps src onto dst, whose palette must be the cache's, like draw.Draw
+// does, through the lookup table if there is one, and remembering the
+// palette index of every other color if nearest is set.
+func (c *buildCache) draw(dst *image.Paletted, src image.Image) {
+	b := dst.Bounds()
+	offset := src.Bounds().Min.Sub(b.Min)
+	pixel := opaquePixels(src)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c.lut != nil {
+				if r, g, bl, opaque := pixel(x+offset.X, y+offset.Y); opaque {
+					if i, ok := c.lut.lookup(r, g, bl); ok {
+						dst.SetColorIndex(x, y, i)
+						continue
+					}
+				}
+			}
+			value := src.At(x+offset.X, y+offset.Y)
+			if c.nearest == nil {
+				dst.SetColorIndex(x, y, uint8(c.palette.Index(value)))
+				continue
+			}
+			c.mu.RLock()
+			i, ok := c.nearest[value]
+			c.mu.RUnlock()
+			if !ok {
+				i = uint8(c.palette.Index(value))
+				c.mu.Lock()
+				if len(c.nearest) < MAX_CACHED_COLORS {
+					c.nearest[value] = i
+				}
+				c.mu.Unlock()
+			}
+			dst.SetColorIndex(x, y, i)
+		}
+	}
+}
+
+// Builder builds panels with the same options, over and over. It works out
+// the palette and the order bricks are tried in once, and remembers the
+// brick color that every image color maps to between panels, so it is
+// cheaper than calling NewPanel for every image. It is safe for concurrent
+// use.
+type Builder struct {
+	opt   Options
+	cache *buildCache
+}
+
+// NewBuilder returns a Builder for opt, which must not change while the
+// Builder is in use.
+func NewBuilder(opt *Options) (*Builder, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	cache := newBuildCache(opt)
+	cache.nearest = make(map[color.Color]uint8)
+	h := newHelper(opt.Bricks, image.NewPaletted(image.Rectangle{}, nil), newPanel(image.Rectangle{}), opt)
+	for brick := range h.bricks {
+		h.candidates(brick.Color)
+	}
+	cache.ordered = h.ordered
+	return &Builder{opt: *opt, cache: cache}, nil
+}
+
+// Build builds a panel reproducing img, like NewPanel.
+func (b *Builder) Build(img image.Image) (*Panel, error) {
+	return b.BuildContext(context.Background(), img, nil)
+}
+
+// BuildContext is like Build, but stops early like NewPanelContext.
+func (b *Builder) BuildContext(ctx context.Context, img image.Image, progress func(done, total int)) (*Panel, error) {
+	return (&buildState{img: img}).build(ctx, &b.opt, b.cache, b.cache, progress)
+}
+
+// Rebuild builds the image prev was built from again with opt, which need
+// not be the Builder's options, redoing only the steps opt changes: the
+// colors stay when only the bricks, in the same colors, BrickWeights,
+// MaxInventory, Substitutions, Placer, Seed or Frame change, and the
+// resized image stays when only the options mapping it to colors, such as
+// Dither or Style, change. prev must have been built by a Builder, which is
+// why panels built by one keep a reference to their image; edits to prev
+// are not carried over.
+func (b *Builder) Rebuild(prev *Panel, opt *Options) (*Panel, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	if prev.state == nil {
+		return nil, errors.New("lego: panel was not built by a Builder")
+	}
+	s := *prev.state
+	palette, _ := paletteOf(opt.Bricks)
+	if !s.opt.sameResize(opt) {
+		s.resized = nil
+	}
+	if !s.opt.sameQuantize(opt) || !equalPalettes(s.palette, palette) {
+		s.dst = nil
+	}
+	// The colors images map to only depend on the palette, but the order
+	// bricks are tried in depends on all of b.opt.
+	var cache *buildCache
+	if equalPalettes(b.cache.palette, palette) {
+		cache = b.cache
+	}
+	return s.build(context.Background(), opt, cache, nil, nil)
+}
+
+// buildState is what Rebuild reuses of a panel built by a Builder: the image
+// and options it was built with, the image resized and its quantized colors.
+type buildState struct {
+	img     image.Image
+	opt     Options
+	resized *resized
+	palette color.Palette
+	dst     *image.Paletted
+	m       map[color.Color]Color
+	overlay map[image.Point]Color
+}
+
+// build is like the function of that name, but skips the steps of s that
+// are already done, quantizing with cache and placing with placeCache.
+func (s *buildState) build(ctx context.Context, opt *Options, cache, placeCache *buildCache, progress func(done, total int)) (*Panel, error) {
+	var err error
+	if s.resized == nil {
+		if s.resized, err = resizeImage(s.img, opt); err != nil {
+			return nil, err
+		}
+		s.dst = nil
+	}
+	if s.dst == nil {
+		if s.dst, s.m, s.overlay, err = s.resized.quantize(opt, cache); err != nil {
+			return nil, err
+		}
+		s.palette, _ = paletteOf(opt.Bricks)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ret, err := place(ctx, s.dst, s.m, opt, placeCache, progress)
+	if err != nil {
+		return nil, err
+	}
+	if s.overlay != nil {
+		// Panels may change their overlay, such as with Embed.
+		ret.overlay = make(map[image.Point]Color, len(s.overlay))
+		for pt, c := range s.overlay {
+			ret.overlay[pt] = c
+		}
+	}
+	s.resized.applyTagMasks(ret, opt.TagMasks)
+	if ret, err = ret.framed(opt); err != nil {
+		return nil, err
+	}
+	s.opt = *opt
+	ret.state = s
+	return ret, nil
+}
+
+// sameResize reports whether opt resizes images like o.
+func (o *Options) sameResize(opt *Options) bool {
+	samePoint := o.Focus == opt.Focus || o.Focus != nil && opt.Focus != nil && *o.Focus == *opt.Focus
+	return o.Width == opt.Width && o.Height == opt.Height && o.Fit == opt.Fit &&
+		o.Background == opt.Background && o.Crop == opt.Crop && o.TrimBorders == opt.TrimBorders &&
+		samePoint && o.Interpolation == opt.Interpolation && o.Adjust == opt.Adjust
+}
+
+// sameQuantize reports whether opt maps images resized alike to the same
+// colors as o, given the same palette.
+func (o *Options) sameQuantize(opt *Options) bool {
+	if len(o.Overlays) != len(opt.Overlays) {
+		return false
+	}
+	for i, c := range o.Overlays {
+		if opt.Overlays[i] != c {
+			return false
+		}
+	}
+	return o.sameResize(opt) && o.Dither == opt.Dither && sameImage(o.Importance, opt.Importance) &&
+		o.Lock == opt.Lock && sameImage(o.Canvas, opt.Canvas) && o.Style == opt.Style
+}
+
+// sameImage reports whether a and b are the same image, as opposed to equal
+// ones.
+func sameImage(a, b image.Image) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b
+}
+
+func equalPalettes(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	img := gradient(96, 64)
+	base := &Options{Width: 24, Bricks: ALL_BRICKS, LUTBits: 5}
+	b, err := NewBuilder(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := b.Build(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := first
+	// Each case rebuilds the panel of the one before, so that every step
+	// Rebuild may reuse is kept from a panel that was itself rebuilt.
+	for _, tc := range []struct {
+		name string
+		opt  Options
+	}{
+		{"no LUT", Options{Width: 24, Bricks: ALL_BRICKS}},
+		{"more shapes", Options{Width: 24, Bricks: EXTENDED_BRICKS}},
+		{"fewer colors", Options{Width: 24, Bricks: BASIC_BRICKS}},
+		{"seed", Options{Width: 24, Bricks: ALL_BRICKS, Seed: 7}},
+		{"dither", Options{Width: 24, Bricks: ALL_BRICKS, Dither: true}},
+		{"style", Options{Width: 24, Bricks: ALL_BRICKS, Style: StyleSpec{Kind: Posterize, Levels: 4}}},
+		{"width and frame", Options{Width: 30, Bricks: ALL_BRICKS, Frame: FrameSpec{Width: 1, Color: BLACK}}},
+		{"overlays", Options{Width: 24, Bricks: ALL_BRICKS, Overlays: []Color{TRANS_RED, TRANS_BLUE}}},
+		{"weights", Options{Width: 24, Bricks: ALL_BRICKS, BrickWeights: map[Brick]float64{{image.Point{1, 1}, WHITE, 0, false}: 50}}},
+	} {
+		want, err := NewPanel(img, &tc.opt)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, err := b.Rebuild(prev, &tc.opt)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !samePanels(want, got) {
+			t.Errorf("%s: rebuilt panel differs from a fresh one", tc.name)
+		}
+		prev = got
+	}
+	if _, err := b.Rebuild(first.FlipH(), base); err == nil {
+		t.Error("rebuilt a panel that was not built by the Builder")
+	}
+}
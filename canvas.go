@@ -0,0 +1,32 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// applyCanvas turns the studs of dst outside canvas, which covers the same
+// area as the source image, into holes: pixels of a color the placer has no
+// bricks for. Overlays over them are dropped. The padding added by Fit is
+// part of the canvas.
+func applyCanvas(dst *image.Paletted, overlay map[image.Point]Color, canvas image.Image, g geometry, src image.Rectangle) error {
+	if len(dst.Palette) >= 256 {
+		return errors.New("lego: too many colors to leave holes for Canvas")
+	}
+	hole := uint8(len(dst.Palette))
+	// The palette may be shared with other panels, so copy it.
+	dst.Palette = append(append(color.Palette(nil), dst.Palette...), color.Transparent)
+	mask := scaleNearest(crop(canvas, g.project(src, canvas.Bounds())), g.size)
+	min := dst.Bounds().Min.Add(g.offset)
+	for y := 0; y < g.size.Y; y++ {
+		for x := 0; x < g.size.X; x++ {
+			if color.GrayModel.Convert(mask.At(x, y)).(color.Gray).Y < 128 {
+				dst.SetColorIndex(min.X+x, min.Y+y, hole)
+				delete(overlay, image.Point{min.X + x, min.Y + y})
+			}
+		}
+	}
+	return nil
+}
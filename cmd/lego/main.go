@@ -0,0 +1,331 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+
+// Command lego builds Lego panels from images.
+//
+// Usage:
+//
+//	lego build input.jpg --width 64 --palette all --dither --out mosaic.png --bom parts.csv
+//	lego serve --addr :8080
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mrbubble/lego"
+	"github.com/mrbubble/lego/legohttp"
+	_ "github.com/mrbubble/lego/render"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var styles = map[string]lego.DrawStyle{
+	"flat":      lego.Flat,
+	"studded":   lego.Studded,
+	"realistic": lego.Realistic,
+}
+
+var fits = map[string]lego.Fit{
+	"stretch": lego.Stretch,
+	"contain": lego.Contain,
+	"cover":   lego.Cover,
+	"pad":     lego.Pad,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: lego build <image> [flags]\n       lego serve [flags]\n")
+	os.Exit(2)
+}
+
+// parseInterspersed parses flags that may appear after positional arguments
+// and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func readImage(path string) image.Image {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	return img
+}
+
+// readSprites reads a sprite atlas from the PNG files in dir named after the
+// brick size they show, such as 2x4.png for a brick 2 studs wide and 4 high.
+func readSprites(dir string) lego.SpriteAtlas {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	atlas := make(lego.SpriteAtlas)
+	for _, path := range paths {
+		var size image.Point
+		if _, err := fmt.Sscanf(filepath.Base(path), "%dx%d.png", &size.X, &size.Y); err != nil {
+			continue
+		}
+		atlas[size] = readImage(path)
+	}
+	if len(atlas) == 0 {
+		log.Fatalf("no sprites in %s", dir)
+	}
+	return atlas
+}
+
+// parseColor parses a color written as #rrggbb, returning nil for "".
+func parseColor(s string) (color.Color, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var r, g, b uint8
+	if n, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil || n != 3 || len(s) != 7 {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{r, g, b, 255}, nil
+}
+
+// export creates the file at path and writes it with fn.
+func export(path string, fn func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := fn(f); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writePNG(path string, img image.Image) {
+	export(path, func(w io.Writer) error {
+		return png.Encode(w, img)
+	})
+}
+
+// writeBOM writes the list of bricks in the format its extension calls for:
+// .tsv, .txt or, by default, CSV.
+func writeBOM(path string, p *lego.Panel) {
+	format := lego.CSV
+	switch filepath.Ext(path) {
+	case ".tsv":
+		format = lego.TSV
+	case ".txt":
+		format = lego.Text
+	}
+	export(path, func(w io.Writer) error {
+		return p.WriteBOM(w, format)
+	})
+}
+
+// errUsage reports command lines that don't make sense.
+var errUsage = errors.New("usage")
+
+// buildCommand is a build command line, parsed.
+type buildCommand struct {
+	file string
+	opt  lego.Options
+	// maxColors, if positive, limits opt.Bricks to the colors that suit the
+	// image best.
+	maxColors int
+	canvas    string
+	mount     int
+	mirror    bool
+	rotate    int
+	preview   bool
+	draw      lego.DrawOptions
+	sprites   string
+	sheetRows int
+
+	// Output files.
+	out, compare, legend, worksheets, gridCSV, indexPNG, bom string
+}
+
+// parseBuild parses the arguments of the build command.
+func parseBuild(args []string) (*buildCommand, error) {
+	cmd := &buildCommand{}
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.UintVar(&cmd.opt.Width, "width", 48, "panel width in studs")
+	fs.UintVar(&cmd.opt.Height, "height", 0, "panel height in studs (default: keep aspect ratio)")
+	fit := fs.String("fit", "stretch", "how to fit both width and height: stretch, contain, cover or pad")
+	palette := fs.String("palette", "basic", "brick palette: basic, advanced, all, extended or greyscale")
+	fs.IntVar(&cmd.maxColors, "colors", 0, "use only this many colors of the palette, those that suit the image best")
+	fs.BoolVar(&cmd.opt.Dither, "dither", false, "use Floyd-Steinberg dithering")
+	fs.StringVar(&cmd.canvas, "canvas", "", "shape the panel with this mask image, leaving its dark or transparent parts empty")
+	fs.IntVar(&cmd.mount, "mount", 0, "put Technic bricks every this many studs along the top edge, to hang the panel")
+	fs.BoolVar(&cmd.mirror, "mirror", false, "flip the panel left to right, to be seen from behind glass")
+	fs.IntVar(&cmd.rotate, "rotate", 0, "turn the panel clockwise this many degrees: 0, 90, 180 or 270")
+	fs.BoolVar(&cmd.preview, "preview", false, "print the panel to the terminal, which must support 24-bit color")
+	fs.StringVar(&cmd.out, "out", "", "write the rendered panel to this PNG file")
+	fs.IntVar(&cmd.draw.Scale, "scale", 16, "pixels per stud in the rendered panel")
+	fs.BoolVar(&cmd.draw.Outline, "outline", true, "outline bricks in the rendered panel")
+	fs.IntVar(&cmd.draw.OutlineWidth, "outline-width", 0, "width of the outline inside each brick, in pixels (default: an eighth of the scale)")
+	outlineColor := fs.String("outline-color", "", "color of the outline, as #rrggbb (default: white)")
+	gapColor := fs.String("gap-color", "", "color of the gap between bricks, as #rrggbb (default: black)")
+	style := fs.String("style", "flat", "rendering style: flat, studded or realistic")
+	fs.BoolVar(&cmd.draw.LabelBricks, "labels", false, "write the color ID on every brick in the rendered panel")
+	fs.BoolVar(&cmd.draw.HighContrast, "high-contrast", false, "draw thick outlines in the rendered panel")
+	fs.IntVar(&cmd.draw.Grid, "grid", 0, "draw a line every this many studs in the rendered panel")
+	fs.BoolVar(&cmd.draw.Rulers, "rulers", false, "number the rows and columns of the rendered panel")
+	fs.StringVar(&cmd.sprites, "sprites", "", "draw bricks with the pictures in this directory, named like 2x4.png")
+	fs.StringVar(&cmd.compare, "compare", "", "write the image and the rendered panel side by side to this PNG file")
+	fs.StringVar(&cmd.legend, "legend", "", "write the key to the color IDs to this PNG file")
+	fs.StringVar(&cmd.worksheets, "worksheets", "", "write row-by-row building sheets to this text file")
+	fs.IntVar(&cmd.sheetRows, "sheet-rows", 16, "rows per building sheet")
+	fs.StringVar(&cmd.gridCSV, "grid-csv", "", "write the color ID of every stud to this CSV file")
+	fs.StringVar(&cmd.indexPNG, "index-png", "", "write the panel at one pixel per stud to this paletted PNG file")
+	fs.StringVar(&cmd.bom, "bom", "", "write the list of bricks to this file (.csv, .tsv or .txt)")
+	files, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return nil, err
+	}
+	// The flag package already reported other errors.
+	if err != nil || len(files) != 1 {
+		return nil, errUsage
+	}
+	cmd.file = files[0]
+	if cmd.rotate%90 != 0 {
+		return nil, fmt.Errorf("cannot rotate by %d degrees", cmd.rotate)
+	}
+	var ok bool
+	if cmd.opt.Bricks, ok = lego.Palettes()[*palette]; !ok {
+		return nil, fmt.Errorf("unknown palette %q", *palette)
+	}
+	if cmd.opt.Fit, ok = fits[*fit]; !ok {
+		return nil, fmt.Errorf("unknown fit %q", *fit)
+	}
+	if cmd.draw.Style, ok = styles[*style]; !ok {
+		return nil, fmt.Errorf("unknown style %q", *style)
+	}
+	if cmd.draw.OutlineColor, err = parseColor(*outlineColor); err != nil {
+		return nil, err
+	}
+	if cmd.draw.GapColor, err = parseColor(*gapColor); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func build(args []string) {
+	cmd, err := parseBuild(args)
+	switch {
+	case err == errUsage:
+		usage()
+	case err == flag.ErrHelp:
+		os.Exit(0)
+	case err != nil:
+		log.Fatal(err)
+	}
+
+	img := readImage(cmd.file)
+	opt := &cmd.opt
+	if cmd.maxColors > 0 {
+		opt.Bricks = lego.ChoosePalette(img, opt.Bricks, cmd.maxColors)
+	}
+	if cmd.canvas != "" {
+		opt.Canvas = readImage(cmd.canvas)
+	}
+	panel, err := lego.NewPanel(img, opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.mirror {
+		panel = panel.FlipH()
+	}
+	for i := 0; i < (cmd.rotate/90%4+4)%4; i++ {
+		panel = panel.Rotate90()
+	}
+	if cmd.mount > 0 {
+		if panel, err = panel.MountingPlan(lego.MountSpec{Interval: cmd.mount}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cmd.preview {
+		if err := panel.RenderANSI(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cmd.out != "" {
+		if cmd.sprites != "" {
+			cmd.draw.Sprites = readSprites(cmd.sprites)
+		}
+		writePNG(cmd.out, panel.DrawWith(&cmd.draw))
+	}
+	if cmd.compare != "" {
+		writePNG(cmd.compare, panel.DrawComparison(img, cmd.draw.Scale))
+	}
+	if cmd.legend != "" {
+		writePNG(cmd.legend, panel.DrawLegend(cmd.draw.Scale))
+	}
+	if cmd.bom != "" {
+		writeBOM(cmd.bom, panel)
+	}
+	if cmd.gridCSV != "" {
+		export(cmd.gridCSV, panel.ExportGridCSV)
+	}
+	if cmd.indexPNG != "" {
+		export(cmd.indexPNG, panel.ExportIndexPNG)
+	}
+	if cmd.worksheets != "" {
+		export(cmd.worksheets, func(w io.Writer) error {
+			return panel.WriteWorksheets(w, cmd.sheetRows)
+		})
+	}
+	size := panel.Size()
+	total := 0
+	for _, n := range panel.CountBricks() {
+		total += n
+	}
+	fmt.Printf("%dx%d panel, %d bricks\n", size.X, size.Y, total)
+}
+
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if args, _ := parseInterspersed(fs, args); len(args) != 0 {
+		usage()
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, (&legohttp.Server{}).Handler()))
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("lego: ")
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "build":
+		build(os.Args[2:])
+	case "serve":
+		serve(os.Args[2:])
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package main
+
+import (
+	"github.com/mrbubble/lego"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBuild(t *testing.T) {
+	cmd, err := parseBuild(strings.Fields("--width 64 in.png --height 32 --fit pad --palette all --dither " +
+		"--colors 6 --rotate 270 --mirror --scale 8 --outline=false --style studded --outline-color #ff8000 " +
+		"--out out.png --bom parts.txt --sheet-rows 8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.file != "in.png" {
+		t.Errorf("file %q, want in.png", cmd.file)
+	}
+	opt := cmd.opt
+	if opt.Width != 64 || opt.Height != 32 || opt.Fit != lego.Pad || !opt.Dither {
+		t.Errorf("options %+v", opt)
+	}
+	if len(opt.Bricks) != len(lego.ALL_BRICKS) || opt.Bricks[0] != lego.ALL_BRICKS[0] {
+		t.Errorf("bricks are not the all palette")
+	}
+	if cmd.maxColors != 6 || cmd.rotate != 270 || !cmd.mirror || cmd.sheetRows != 8 {
+		t.Errorf("command %+v", cmd)
+	}
+	if cmd.out != "out.png" || cmd.bom != "parts.txt" || cmd.compare != "" {
+		t.Errorf("output files %q, %q, %q", cmd.out, cmd.bom, cmd.compare)
+	}
+	draw := cmd.draw
+	if draw.Scale != 8 || draw.Outline || draw.Style != lego.Studded || draw.GapColor != nil ||
+		draw.OutlineColor != (color.NRGBA{255, 128, 0, 255}) {
+		t.Errorf("draw options %+v", draw)
+	}
+
+	// Defaults.
+	if cmd, err = parseBuild([]string{"in.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.opt.Width != 48 || cmd.opt.Height != 0 || cmd.opt.Fit != lego.Stretch || len(cmd.opt.Bricks) != len(lego.BASIC_BRICKS) ||
+		cmd.draw.Scale != 16 || !cmd.draw.Outline || cmd.draw.Style != lego.Flat || cmd.sheetRows != 16 {
+		t.Errorf("default command %+v", cmd)
+	}
+
+	for _, args := range []string{
+		"",
+		"a.png b.png",
+		"in.png --palette neon",
+		"in.png --fit squash",
+		"in.png --style cubist",
+		"in.png --rotate 45",
+		"in.png --gap-color red",
+		"in.png --width wide",
+	} {
+		if _, err := parseBuild(strings.Fields(args)); err == nil {
+			t.Errorf("parsed %q", args)
+		}
+	}
+}
+
+func TestWriteBOM(t *testing.T) {
+	p, err := lego.NewPanel(image.NewGray(image.Rect(0, 0, 8, 4)), &lego.Options{Width: 4, Height: 2, Bricks: lego.BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "lego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tc := range []struct {
+		file, header string
+	}{
+		{"parts.csv", "Size,Color,Count\n"},
+		{"parts.tsv", "Size\tColor\tCount\n"},
+		{"parts.txt", "Size  Color"},
+	} {
+		path := filepath.Join(dir, tc.file)
+		writeBOM(path, p)
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(got), tc.header) {
+			t.Errorf("%s starts %q, want %q", tc.file, got, tc.header)
+		}
+	}
+}
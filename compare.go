@@ -0,0 +1,69 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"github.com/nfnt/resize"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// comparison returns the panel drawn at scale, which is at least 1, and src
+// resized to the same size.
+func (p *Panel) comparison(src image.Image, scale int) (mosaic, original image.Image, stud int) {
+	if scale < 1 {
+		scale = 1
+	}
+	mosaic = p.DrawWith(&DrawOptions{Scale: scale, Outline: true})
+	size := mosaic.Bounds().Size()
+	original = resize.Resize(uint(size.X), uint(size.Y), src, resize.Lanczos3)
+	return mosaic, original, scale
+}
+
+// DrawComparison renders src, stretched to the size of the rendered panel,
+// and the panel drawn at scale side by side, separated by a white stud-wide
+// gap, to judge settings or share the result. src is usually the image the
+// panel was built from, already cropped or padded like Options.Fit does.
+func (p *Panel) DrawComparison(src image.Image, scale int) image.Image {
+	mosaic, original, gap := p.comparison(src, scale)
+	size := mosaic.Bounds().Size()
+	out := image.NewNRGBA(image.Rect(0, 0, 2*size.X+gap, size.Y))
+	draw.Draw(out, out.Bounds(), image.White, image.ZP, draw.Src)
+	draw.Draw(out, image.Rectangle{image.ZP, size}, original, original.Bounds().Min, draw.Src)
+	draw.Draw(out, image.Rectangle{image.Point{size.X + gap, 0}, image.Point{2*size.X + gap, size.Y}},
+		mosaic, image.ZP, draw.Over)
+	return out
+}
+
+// DrawComparisonFrames renders n frames, such as for an animation, of a
+// slider sweeping from left to right over the panel drawn at scale: left of
+// the slider, src shows through, blended into the panel within a stud of
+// it. The first frame shows only the panel and the last only src.
+func (p *Panel) DrawComparisonFrames(src image.Image, scale, n int) []image.Image {
+	mosaic, original, blend := p.comparison(src, scale)
+	size := mosaic.Bounds().Size()
+	frames := make([]image.Image, 0, n)
+	for i := 0; i < n; i++ {
+		// at is where the slider is, from -blend to size.X+blend.
+		at := -blend
+		if n > 1 {
+			at += i * (size.X + 2*blend) / (n - 1)
+		}
+		out := image.NewNRGBA(image.Rectangle{image.ZP, size})
+		draw.Draw(out, out.Bounds(), mosaic, image.ZP, draw.Src)
+		for x := 0; x < size.X && x < at+blend; x++ {
+			alpha := uint8(255)
+			if d := at + blend - x; d < 2*blend {
+				alpha = uint8(255 * d / (2 * blend))
+			}
+			column := image.Rect(x, 0, x+1, size.Y)
+			draw.DrawMask(out, column, original, original.Bounds().Min.Add(image.Point{x, 0}),
+				&image.Uniform{color.Alpha{alpha}}, image.ZP, draw.Over)
+		}
+		if at >= 0 && at < size.X {
+			draw.Draw(out, image.Rect(at, 0, at+1, size.Y), image.White, image.ZP, draw.Src)
+		}
+		frames = append(frames, out)
+	}
+	return frames
+}
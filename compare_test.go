@@ -0,0 +1,91 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// near reports whether the colors differ by at most tol in every channel.
+func near(a, b color.Color, tol int) bool {
+	c, d := color.NRGBAModel.Convert(a).(color.NRGBA), color.NRGBAModel.Convert(b).(color.NRGBA)
+	for _, diff := range []int{
+		int(c.R) - int(d.R), int(c.G) - int(d.G), int(c.B) - int(d.B), int(c.A) - int(d.A),
+	} {
+		if diff < -tol || diff > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDrawComparison(t *testing.T) {
+	// At scale 2 the panel is drawn as large as the image, so the source
+	// shows up almost unchanged.
+	src := gradient(32, 16)
+	p, err := NewPanel(src, &Options{Width: 16, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const scale = 2
+	mosaic := p.DrawWith(&DrawOptions{Scale: scale, Outline: true})
+	// half tells which of the two images, if any, pixel x of a row should
+	// show, and from which column.
+	check := func(name string, img image.Image, half func(x int) (image.Image, int)) {
+		if got, want := img.Bounds().Dy(), 16; got != want {
+			t.Fatalf("%s is %d pixels high, want %d", name, got, want)
+		}
+		for y := 0; y < 16; y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				switch want, at := half(x); want {
+				case src:
+					if !near(img.At(x, y), src.At(at, y), 8) {
+						t.Fatalf("%s: %v at (%d, %d), want %v from the source", name, img.At(x, y), x, y, src.At(at, y))
+					}
+				case mosaic:
+					if !near(img.At(x, y), mosaic.At(at, y), 0) {
+						t.Fatalf("%s: %v at (%d, %d), want %v from the panel", name, img.At(x, y), x, y, mosaic.At(at, y))
+					}
+				case image.White:
+					if !near(img.At(x, y), color.White, 0) {
+						t.Fatalf("%s: %v at (%d, %d), want white", name, img.At(x, y), x, y)
+					}
+				}
+			}
+		}
+	}
+
+	cmp := p.DrawComparison(src, scale)
+	if got, want := cmp.Bounds().Dx(), 2*32+scale; got != want {
+		t.Fatalf("comparison is %d pixels wide, want %d", got, want)
+	}
+	check("comparison", cmp, func(x int) (image.Image, int) {
+		switch {
+		case x < 32:
+			return src, x
+		case x < 32+scale:
+			return image.White, x
+		}
+		return mosaic, x - 32 - scale
+	})
+
+	frames := p.DrawComparisonFrames(src, scale, 5)
+	if len(frames) != 5 {
+		t.Fatalf("%d frames, want 5", len(frames))
+	}
+	check("first frame", frames[0], func(x int) (image.Image, int) { return mosaic, x })
+	check("last frame", frames[4], func(x int) (image.Image, int) { return src, x })
+	// Halfway, the slider is at x = 16, blending within a stud of it.
+	check("middle frame", frames[2], func(x int) (image.Image, int) {
+		switch {
+		case x < 16-scale:
+			return src, x
+		case x == 16:
+			return image.White, x
+		case x > 16+scale:
+			return mosaic, x
+		}
+		return nil, x
+	})
+}
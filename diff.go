@@ -0,0 +1,102 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// PanelDiff describes how to turn one panel into another.
+type PanelDiff struct {
+	to *Panel
+	// Changed lists the studs whose color differs, in row-major order.
+	Changed []image.Point
+	// Removed and Added hold the bricks, by position, that must be taken
+	// off the first panel and put on the second. Bricks at the same place
+	// in both panels are kept.
+	Removed map[image.Point]Brick
+	Added   map[image.Point]Brick
+}
+
+func colorAt(p *Panel, cells *cellGrid, pt image.Point) (Color, bool) {
+	pos, ok := cells.at(pt)
+	if !ok {
+		return Color{}, false
+	}
+	return p.bricks.at(pos).Color, true
+}
+
+// Diff compares two panels, usually built from the same image with
+// different settings.
+func Diff(a, b *Panel) *PanelDiff {
+	d := &PanelDiff{
+		to:      b,
+		Removed: make(map[image.Point]Brick),
+		Added:   make(map[image.Point]Brick),
+	}
+	ca, cb := a.cells(), b.cells()
+	r := a.bounds.Union(b.bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			pt := image.Point{x, y}
+			colorA, okA := colorAt(a, ca, pt)
+			colorB, okB := colorAt(b, cb, pt)
+			if okA != okB || colorA != colorB {
+				d.Changed = append(d.Changed, pt)
+			}
+		}
+	}
+	a.bricks.each(func(pos image.Point, brick *Brick) {
+		if other := b.bricks.at(pos); other == nil || *other != *brick {
+			d.Removed[pos] = *brick
+		}
+	})
+	b.bricks.each(func(pos image.Point, brick *Brick) {
+		if other := a.bricks.at(pos); other == nil || *other != *brick {
+			d.Added[pos] = *brick
+		}
+	})
+	return d
+}
+
+// Parts returns the change in brick counts: positive for bricks that must
+// be acquired, negative for bricks that are freed.
+func (d *PanelDiff) Parts() map[Brick]int {
+	result := make(map[Brick]int)
+	for _, brick := range d.Added {
+		result[brick.canonical()]++
+	}
+	for _, brick := range d.Removed {
+		result[brick.canonical()]--
+	}
+	for brick, n := range result {
+		if n == 0 {
+			delete(result, brick)
+		}
+	}
+	return result
+}
+
+// Draw renders the second panel with the bricks it shares with the first
+// one faded out, so the new bricks stand out.
+func (d *PanelDiff) Draw(scale int) image.Image {
+	out := d.to.Draw(scale, true).(draw.Image)
+	fade := &image.Uniform{color.NRGBA{255, 255, 255, 192}}
+	d.to.bricks.each(func(pos image.Point, brick *Brick) {
+		if _, ok := d.Added[pos]; ok {
+			return
+		}
+		for y := 0; y < brick.Size.Y; y++ {
+			for x := 0; x < brick.Size.X; x++ {
+				if brick.Covers(image.Point{x, y}) {
+					min := pos.Add(image.Point{x, y}.Sub(brick.anchor())).Mul(scale)
+					draw.Draw(out, image.Rectangle{min, min.Add(image.Point{scale, scale})}, fade,
+						image.ZP, draw.Over)
+				}
+			}
+		}
+	})
+
+	return out
+}
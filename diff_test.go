@@ -0,0 +1,59 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+// blocks returns a w x h image of white with a red rectangle at r, one pixel
+// per stud.
+func blocks(w, h int, r image.Rectangle) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{WHITE.color}, image.ZP, draw.Src)
+	draw.Draw(img, r, &image.Uniform{BRIGHT_RED.color}, image.ZP, draw.Src)
+	return img
+}
+
+func TestDiff(t *testing.T) {
+	opt := &Options{Width: 8, Bricks: BASIC_BRICKS}
+	white, err := NewPanel(blocks(8, 4, image.Rectangle{}), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		red     image.Rectangle
+		changed []image.Point
+	}{
+		{"same", image.Rectangle{}, nil},
+		{"one stud", image.Rect(7, 3, 8, 4), []image.Point{{7, 3}}},
+		{"block", image.Rect(2, 1, 4, 3), []image.Point{{2, 1}, {3, 1}, {2, 2}, {3, 2}}},
+	} {
+		p, err := NewPanel(blocks(8, 4, tc.red), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := Diff(white, p)
+		if !reflect.DeepEqual(d.Changed, tc.changed) {
+			t.Errorf("%s: changed %v, want %v", tc.name, d.Changed, tc.changed)
+		}
+		// Bricks kept in place are in both panels, so the parts to get
+		// and free are the difference in brick counts.
+		want := p.CountBricks()
+		for brick, n := range white.CountBricks() {
+			if want[brick] -= n; want[brick] == 0 {
+				delete(want, brick)
+			}
+		}
+		if got := d.Parts(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: parts %v, want %v", tc.name, got, want)
+		}
+		back := Diff(p, white)
+		if !reflect.DeepEqual(back.Added, d.Removed) || !reflect.DeepEqual(back.Removed, d.Added) {
+			t.Errorf("%s: diffing back doesn't swap added and removed bricks", tc.name)
+		}
+	}
+}
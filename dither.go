@@ -0,0 +1,159 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// importanceMap maps mask, which covers the same area as the source image,
+// onto dst. The padding added by Fit has no importance.
+func importanceMap(mask image.Image, g geometry, src image.Rectangle, dst image.Rectangle) *image.Gray {
+	scaled := scaleNearest(crop(mask, g.project(src, mask.Bounds())), g.size)
+	out := image.NewGray(dst)
+	min := dst.Min.Add(g.offset)
+	for y := 0; y < g.size.Y; y++ {
+		for x := 0; x < g.size.X; x++ {
+			out.Set(min.X+x, min.Y+y, scaled.At(x, y))
+		}
+	}
+	return out
+}
+
+// IMPORTANCE_TOLERANCE is how much further, as a fraction of the distance
+// from black to white, the color of the stud to the left or above may be
+// than the closest one for an unimportant stud to take it instead. It
+// narrows down to nothing as importance grows.
+var IMPORTANCE_TOLERANCE = 0.08
+
+// ditherImportance is Floyd-Steinberg dithering where each pixel only takes
+// in the error from its neighbors in proportion to how unimportant it is.
+// Important pixels get the closest color to their own, while the error they
+// leave is still passed on to the pixels around them. Unimportant pixels
+// also accept the color of their left or top neighbor within
+// IMPORTANCE_TOLERANCE, so that backgrounds form larger patches.
+func ditherImportance(dst *image.Paletted, src image.Image, importance *image.Gray) {
+	palette := make([][3]float64, len(dst.Palette))
+	for i, c := range dst.Palette {
+		r, g, b, _ := c.RGBA()
+		palette[i] = [3]float64{float64(r), float64(g), float64(b)}
+	}
+	b := dst.Bounds()
+	w := b.Dx()
+	rgba := image.NewRGBA64(b)
+	draw.Draw(rgba, b, src, src.Bounds().Min, draw.Src)
+	// Errors for the current and next rows, with a pixel of margin at
+	// each end.
+	cur := make([][3]float64, w+2)
+	next := make([][3]float64, w+2)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := x - b.Min.X + 1
+			strength := 1 - float64(importance.GrayAt(x, y).Y)/255
+			c := rgba.RGBA64At(x, y)
+			v := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+			for k := range v {
+				v[k] = math.Max(0, math.Min(0xffff, v[k]+strength*cur[i][k]))
+			}
+			dist := func(j int) float64 {
+				d := 0.0
+				for k, p := range palette[j] {
+					d += (v[k] - p) * (v[k] - p)
+				}
+				return math.Sqrt(d)
+			}
+			best, bestDist := 0, math.Inf(1)
+			for j := range palette {
+				if d := dist(j); d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+			tolerance := strength * IMPORTANCE_TOLERANCE * math.Sqrt(3) * 0xffff
+			for _, n := range []image.Point{{x - 1, y}, {x, y - 1}} {
+				if !n.In(b) {
+					continue
+				}
+				j := int(dst.ColorIndexAt(n.X, n.Y))
+				if d := dist(j); j != best && d <= bestDist+tolerance {
+					best, bestDist = j, d
+					break
+				}
+			}
+			dst.SetColorIndex(x, y, uint8(best))
+			for k := range v {
+				e := v[k] - palette[best][k]
+				cur[i+1][k] += e * 7 / 16
+				next[i-1][k] += e * 3 / 16
+				next[i][k] += e * 5 / 16
+				next[i+1][k] += e * 1 / 16
+			}
+		}
+		cur, next = next, cur
+		for i := range next {
+			next[i] = [3]float64{}
+		}
+	}
+}
+
+// Saliency estimates which parts of img draw the eye, for use as
+// Options.Importance: areas with fine detail and strong local contrast, such
+// as eyes and text, are bright, while flat areas and smooth gradients are
+// dark.
+func Saliency(img image.Image) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = float64(color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y)
+		}
+	}
+	radius := w
+	if h < radius {
+		radius = h
+	}
+	radius = radius/32 + 1
+	// Center-surround difference, spread over the surrounding area.
+	surround := boxBlur(lum, w, h, radius)
+	for i := range lum {
+		lum[i] = math.Abs(lum[i] - surround[i])
+	}
+	lum = boxBlur(lum, w, h, radius)
+	max := 0.0
+	for _, v := range lum {
+		max = math.Max(max, v)
+	}
+	out := image.NewGray(b)
+	if max == 0 {
+		return out
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Pix[y*out.Stride+x] = uint8(255 * lum[y*w+x] / max)
+		}
+	}
+	return out
+}
+
+// boxBlur averages every value of pix over a square of the given radius,
+// using a summed-area table.
+func boxBlur(pix []float64, w, h, radius int) []float64 {
+	sum := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum[(y+1)*(w+1)+x+1] = pix[y*w+x] + sum[y*(w+1)+x+1] + sum[(y+1)*(w+1)+x] - sum[y*(w+1)+x]
+		}
+	}
+	out := make([]float64, len(pix))
+	for y := 0; y < h; y++ {
+		y0, y1 := clampInt(y-radius, 0, h), clampInt(y+radius+1, 0, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := clampInt(x-radius, 0, w), clampInt(x+radius+1, 0, w)
+			total := sum[y1*(w+1)+x1] - sum[y0*(w+1)+x1] - sum[y1*(w+1)+x0] + sum[y0*(w+1)+x0]
+			out[y*w+x] = total / float64((x1-x0)*(y1-y0))
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// colorChanges counts the pairs of neighboring studs of p in different
+// colors.
+func colorChanges(p *Panel) int {
+	colors := make(map[image.Point]Color)
+	p.cells().each(func(pt, pos image.Point) {
+		colors[pt] = p.bricks.at(pos).Color
+	})
+	n := 0
+	for pt, c := range colors {
+		for _, d := range []image.Point{{1, 0}, {0, 1}} {
+			if other, ok := colors[pt.Add(d)]; ok && other != c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestDitherImportance(t *testing.T) {
+	defer func(tolerance float64) { IMPORTANCE_TOLERANCE = tolerance }(IMPORTANCE_TOLERANCE)
+	img := gradient(120, 90)
+	build := func(importance uint8, tolerance float64) *Panel {
+		IMPORTANCE_TOLERANCE = tolerance
+		mask := image.NewGray(img.Bounds())
+		draw.Draw(mask, mask.Bounds(), &image.Uniform{color.Gray{importance}}, image.ZP, draw.Src)
+		p, err := NewPanel(img, &Options{Width: 40, Bricks: ALL_BRICKS, Dither: true, Importance: mask})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	plain, err := NewPanel(img, &Options{Width: 40, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tolerance := range []float64{0, 0.08, 0.5} {
+		if !samePanels(build(255, tolerance), plain) {
+			t.Errorf("tolerance %v: important studs don't get their closest colors", tolerance)
+		}
+	}
+	strict, loose := colorChanges(build(0, 0)), colorChanges(build(0, 0.08))
+	if loose >= strict {
+		t.Errorf("%d color changes with a tolerance, %d without", loose, strict)
+	}
+}
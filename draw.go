@@ -0,0 +1,221 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+)
+
+// DrawStyle selects how bricks look in DrawWith.
+type DrawStyle int
+
+const (
+	// Flat draws every brick as a plain rectangle.
+	Flat DrawStyle = iota
+	// Studded adds a shaded stud on every cell.
+	Studded
+	// Realistic adds bevels, highlights, plastic texture and shadows. It is
+	// provided by the github.com/mrbubble/lego/render package, which must
+	// be imported for it to be available; otherwise panels are drawn
+	// Studded.
+	Realistic
+)
+
+// DrawOptions configures DrawWith.
+type DrawOptions struct {
+	Scale int // Pixels per stud; at least 1.
+	// Outline draws a seam along the edges of every brick: a gap in
+	// GapColor, black by default, around a line in OutlineColor, white by
+	// default. OutlineWidth is the width of the seam inside each brick, in
+	// pixels, and defaults to an eighth of Scale; the gap takes half of it,
+	// rounded up. The seam always leaves some of the brick color.
+	Outline      bool
+	OutlineWidth int
+	OutlineColor color.Color
+	GapColor     color.Color
+	Style        DrawStyle
+	// LabelBricks writes the LEGO color ID on every brick, for builders
+	// who can't tell some colors apart. DrawLegend renders the key.
+	LabelBricks bool
+	// HighContrast draws a thick black line along the edges of every
+	// brick.
+	HighContrast bool
+	// Grid, if positive, draws a line every Grid studs, usually 8 or 16 to
+	// match baseplates.
+	Grid int
+	// Rulers adds the column numbers along the top and the row numbers
+	// along the left side, counting from 1, which makes the image larger.
+	Rulers bool
+	// Sprites, if set, draws the bricks it has a picture of with it in the
+	// Flat and Studded styles, instead of as rectangles, without outline
+	// or studs.
+	Sprites SpriteAtlas
+}
+
+var (
+	stylesMu sync.Mutex
+	styles   = make(map[DrawStyle]func(*Panel, *DrawOptions) image.Image)
+)
+
+// RegisterStyle makes a drawing style available to DrawWith. It is meant to
+// be called from the init function of packages providing styles.
+func RegisterStyle(style DrawStyle, fn func(p *Panel, opt *DrawOptions) image.Image) {
+	stylesMu.Lock()
+	styles[style] = fn
+	stylesMu.Unlock()
+}
+
+// DrawWith renders the panel.
+func (p *Panel) DrawWith(opt *DrawOptions) image.Image {
+	if opt.Scale < 1 {
+		o := *opt
+		o.Scale = 1
+		opt = &o
+	}
+	var out image.Image
+	if p.round {
+		out = p.drawDots(opt.Scale)
+	} else if opt.Style != Flat && opt.Style != Studded {
+		stylesMu.Lock()
+		fn := styles[opt.Style]
+		stylesMu.Unlock()
+		if fn != nil {
+			out = fn(p, opt)
+		}
+	}
+	if out == nil {
+		out = p.drawStudded(opt)
+	}
+	if dst, ok := out.(draw.Image); ok {
+		if opt.HighContrast {
+			p.drawHighContrast(dst, opt.Scale)
+		}
+		if opt.LabelBricks {
+			p.drawLabels(dst, opt.Scale)
+		}
+		if opt.Grid > 0 {
+			p.drawGrid(dst, opt.Grid, opt.Scale)
+		}
+	}
+	if opt.Rulers {
+		return p.withRulers(out, opt.Scale)
+	}
+	return out
+}
+
+// drawStudded renders the panel in the Flat and Studded styles.
+func (p *Panel) drawStudded(opt *DrawOptions) image.Image {
+	scale := opt.Scale
+	// Studs without a brick are left transparent.
+	out := image.NewNRGBA(image.Rectangle{image.ZP, p.bounds.Size().Mul(scale)})
+	sprites := &sprites{atlas: opt.Sprites, scale: scale}
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		if !sprites.draw(out, pos, brick) {
+			drawBrick(out, pos, brick, scale, opt.seam())
+			if opt.Style != Flat {
+				drawStuds(out, pos, brick, scale)
+			}
+		}
+		if brick.Technic {
+			drawHoles(out, pos, brick, scale)
+		}
+	})
+	p.drawOverlay(out, scale)
+	return out
+}
+
+// seam is the outline of DrawOptions: gap pixels of the gap color and then
+// line pixels of the line color along the inside of every brick edge.
+type seam struct {
+	gap, line           int
+	gapColor, lineColor color.Color
+}
+
+// newSeam returns the seam of the given width at scale, or the default one
+// if width is 0, with the default colors for nil ones.
+func newSeam(scale, width int, gapColor, lineColor color.Color) seam {
+	if width <= 0 {
+		width = (scale + 4) / 8
+	}
+	if max := (scale - 1) / 2; width > max {
+		width = max
+	}
+	if gapColor == nil {
+		gapColor = color.NRGBA{0, 0, 0, 255}
+	}
+	if lineColor == nil {
+		lineColor = color.NRGBA{255, 255, 255, 255}
+	}
+	return seam{(width + 1) / 2, width / 2, gapColor, lineColor}
+}
+
+// seam returns the outline to draw, which is empty without Outline.
+func (opt *DrawOptions) seam() seam {
+	if !opt.Outline {
+		return seam{}
+	}
+	return newSeam(opt.Scale, opt.OutlineWidth, opt.GapColor, opt.OutlineColor)
+}
+
+// circle is an alpha mask of a disc.
+type circle struct {
+	center image.Point
+	r      int
+}
+
+func (c *circle) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (c *circle) Bounds() image.Rectangle {
+	return image.Rect(c.center.X-c.r, c.center.Y-c.r, c.center.X+c.r, c.center.Y+c.r)
+}
+
+func (c *circle) At(x, y int) color.Color {
+	xx, yy, rr := float64(x-c.center.X)+0.5, float64(y-c.center.Y)+0.5, float64(c.r)
+	if xx*xx+yy*yy < rr*rr {
+		return color.Alpha{255}
+	}
+	return color.Alpha{0}
+}
+
+// shade scales the color's components by f, clamping them.
+func shade(c color.Color, f float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	scale := func(v uint8) uint8 {
+		s := float64(v) * f
+		if s > 255 {
+			return 255
+		}
+		return uint8(s)
+	}
+	return color.NRGBA{scale(n.R), scale(n.G), scale(n.B), n.A}
+}
+
+// drawStuds draws a stud, lit from the top left, on every cell of a brick.
+func drawStuds(out draw.Image, pos image.Point, brick *Brick, scale int) {
+	r := scale * 3 / 10
+	if r < 2 {
+		return
+	}
+	dark := &image.Uniform{shade(brick.Color.color, 0.7)}
+	light := &image.Uniform{shade(brick.Color.color, 1.15)}
+	offset := image.Point{r / 4, r / 4}
+	if offset.X == 0 {
+		offset = image.Point{1, 1}
+	}
+	for y := 0; y < brick.Size.Y; y++ {
+		for x := 0; x < brick.Size.X; x++ {
+			if !brick.Covers(image.Point{x, y}) {
+				continue
+			}
+			center := pos.Add(image.Point{x, y}.Sub(brick.anchor())).Mul(scale).Add(image.Point{scale / 2, scale / 2})
+			shadow := &circle{center.Add(offset), r}
+			draw.DrawMask(out, shadow.Bounds(), dark, image.ZP, shadow, shadow.Bounds().Min, draw.Over)
+			top := &circle{center, r}
+			draw.DrawMask(out, top.Bounds(), light, image.ZP, top, top.Bounds().Min, draw.Over)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+)
+
+func (p *Panel) find(pt image.Point) (image.Point, bool) {
+	found, ok := image.ZP, false
+	p.bricks.around(image.Rectangle{pt, pt.Add(image.Point{1, 1})}, func(pos image.Point, brick *Brick) {
+		if !ok && brick.Covers(pt.Sub(pos).Add(brick.anchor())) {
+			found, ok = pos, true
+		}
+	})
+	return found, ok
+}
+
+// BrickAt returns the brick covering the stud at pt and the position of its
+// top-left stud.
+func (p *Panel) BrickAt(pt image.Point) (*Brick, image.Point, bool) {
+	pos, ok := p.find(pt)
+	if !ok {
+		return nil, image.ZP, false
+	}
+	brick := *p.bricks.at(pos)
+	return &brick, pos, true
+}
+
+// Remove takes off the brick covering the stud at pt, with its tags,
+// leaving a hole, and reports whether there was one.
+func (p *Panel) Remove(pt image.Point) bool {
+	pos, ok := p.find(pt)
+	if ok {
+		p.bricks.set(pos, nil)
+		delete(p.tags, pos)
+	}
+	return ok
+}
+
+// Place puts a brick with its top-left stud at pos. The brick must lie
+// inside the panel and not overlap other bricks.
+func (p *Panel) Place(pos image.Point, b Brick) error {
+	if b.Size.X <= 0 || b.Size.Y <= 0 || b.Color.color == nil || b.Mask != 0 && b.Size.X*b.Size.Y > 64 {
+		return fmt.Errorf("lego: invalid brick %v", b)
+	}
+	min := pos.Sub(b.anchor())
+	r := image.Rectangle{min, min.Add(b.Size)}
+	if !r.In(p.bounds) {
+		return fmt.Errorf("lego: %v at %v is outside the panel", b, pos)
+	}
+	var err error
+	p.bricks.around(r, func(other image.Point, brick *Brick) {
+		if err == nil && overlaps(b, pos, *brick, other) {
+			err = fmt.Errorf("lego: %v at %v overlaps %v at %v", b, pos, *brick, other)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	p.bricks.set(pos, &b)
+	return nil
+}
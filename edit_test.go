@@ -0,0 +1,89 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPlace(t *testing.T) {
+	corner := Brick{image.Point{2, 2}, BLACK, CORNER, false}
+	// gap is the stud of the 2x2 square of the corner that it leaves free.
+	var gap image.Point
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			if !corner.Covers(image.Point{x, y}) {
+				gap = image.Point{x, y}
+			}
+		}
+	}
+	cornerPos := image.Point{6, 0}.Add(corner.anchor())
+	for _, tc := range []struct {
+		name    string
+		pos     image.Point
+		brick   Brick
+		wantErr bool
+	}{
+		{"free spot", image.Point{0, 0}, Brick{image.Point{2, 1}, WHITE, 0, false}, false},
+		{"touching", image.Point{4, 2}, Brick{image.Point{1, 2}, WHITE, 0, false}, false},
+		{"same spot", image.Point{2, 2}, Brick{image.Point{1, 1}, WHITE, 0, false}, true},
+		{"partial overlap", image.Point{3, 3}, Brick{image.Point{2, 2}, WHITE, 0, false}, true},
+		{"covering", image.Point{1, 1}, Brick{image.Point{4, 4}, WHITE, 0, false}, true},
+		{"corner gap", image.Point{6, 0}.Add(gap), Brick{image.Point{1, 1}, WHITE, 0, false}, false},
+		{"corner stud", cornerPos, Brick{image.Point{1, 1}, WHITE, 0, false}, true},
+		{"outside", image.Point{7, 7}, Brick{image.Point{2, 1}, WHITE, 0, false}, true},
+		{"negative", image.Point{-1, 0}, Brick{image.Point{1, 1}, WHITE, 0, false}, true},
+		{"empty size", image.Point{0, 5}, Brick{image.Point{0, 1}, WHITE, 0, false}, true},
+		{"no color", image.Point{0, 5}, Brick{image.Point{1, 1}, Color{}, 0, false}, true},
+	} {
+		p := newPanel(image.Rect(0, 0, 8, 8))
+		if err := p.Place(image.Point{2, 2}, Brick{image.Point{2, 2}, BRIGHT_RED, 0, false}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Place(cornerPos, corner); err != nil {
+			t.Fatal(err)
+		}
+		err := p.Place(tc.pos, tc.brick)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: Place(%v, %v) = %v, want error %v", tc.name, tc.pos, tc.brick, err, tc.wantErr)
+		}
+		if want := 2; err == nil {
+			want++
+			if b, _, ok := p.BrickAt(tc.pos); !ok || *b != tc.brick {
+				t.Errorf("%s: brick at %v is %v", tc.name, tc.pos, b)
+			}
+		} else if p.bricks.len() != want {
+			t.Errorf("%s: %d bricks after a failed Place, want %d", tc.name, p.bricks.len(), want)
+		}
+	}
+}
+
+func TestBrickAtRemove(t *testing.T) {
+	p := newPanel(image.Rect(0, 0, 8, 8))
+	big := Brick{image.Point{4, 2}, WHITE, 0, false}
+	if err := p.Place(image.Point{1, 1}, big); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		pt      image.Point
+		wantPos image.Point
+		ok      bool
+	}{
+		{image.Point{1, 1}, image.Point{1, 1}, true},
+		{image.Point{4, 2}, image.Point{1, 1}, true},
+		{image.Point{5, 2}, image.ZP, false},
+		{image.Point{0, 0}, image.ZP, false},
+		{image.Point{-3, 20}, image.ZP, false},
+	} {
+		b, pos, ok := p.BrickAt(tc.pt)
+		if ok != tc.ok || ok && (pos != tc.wantPos || *b != big) {
+			t.Errorf("BrickAt(%v) = %v, %v, %v", tc.pt, b, pos, ok)
+		}
+	}
+	if p.Remove(image.Point{0, 0}) {
+		t.Error("removed a brick from an empty stud")
+	}
+	if !p.Remove(image.Point{3, 2}) || p.bricks.len() != 0 {
+		t.Error("brick not removed")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// EmbedMode selects what Panel.Embed stamps.
+type EmbedMode int
+
+const (
+	// EmbedOpaque stamps all of the signature, in its own colors.
+	EmbedOpaque EmbedMode = iota
+	// EmbedMark stamps only the studs of the signature's least used color,
+	// such as the letters of a text panel, in that color.
+	EmbedMark
+	// EmbedContrast is like EmbedMark, but uses the color of the panel or
+	// the signature that stands out the most from the studs underneath.
+	EmbedContrast
+)
+
+// Embed stamps sig, such as initials or a date from NewTextPanel, into the
+// panel with its top-left corner at the stud at. The bricks under the stamp
+// are laid again together with it, in the shapes already used by either
+// panel, so the stamp merges into the layout; overlay plates under it, and
+// the tags of the bricks laid again, are removed.
+func (p *Panel) Embed(sig *Panel, at image.Point, mode EmbedMode) error {
+	if !sig.bounds.Sub(sig.bounds.Min).Add(at).In(p.bounds) {
+		return errors.New("lego: signature does not fit in the panel there")
+	}
+	stamp := make(map[image.Point]Color)
+	used := make(map[Color]int)
+	sig.cells().each(func(pt, pos image.Point) {
+		c := sig.bricks.at(pos).Color
+		stamp[pt.Sub(sig.bounds.Min).Add(at)] = c
+		used[c]++
+	})
+	if mode != EmbedOpaque && len(used) > 0 {
+		var mark Color
+		for c, n := range used {
+			if mark.color == nil || n < used[mark] || n == used[mark] && c.name < mark.name {
+				mark = c
+			}
+		}
+		for pt, c := range stamp {
+			if c != mark {
+				delete(stamp, pt)
+			}
+		}
+		if mode == EmbedContrast {
+			mark = p.contrasting(stamp, sig)
+			for pt := range stamp {
+				stamp[pt] = mark
+			}
+		}
+	}
+
+	// The bricks to lay again: those under the stamp, and the stamp.
+	cells := p.cells()
+	colors := make(map[image.Point]Color)
+	for pt := range stamp {
+		pos, ok := cells.at(pt)
+		if !ok {
+			continue
+		}
+		brick := p.bricks.at(pos)
+		if brick == nil {
+			continue
+		}
+		for y := 0; y < brick.Size.Y; y++ {
+			for x := 0; x < brick.Size.X; x++ {
+				if brick.Covers(image.Point{x, y}) {
+					colors[pos.Add(image.Point{x, y}.Sub(brick.anchor()))] = brick.Color
+				}
+			}
+		}
+		p.bricks.set(pos, nil)
+		delete(p.tags, pos)
+	}
+	for pt, c := range stamp {
+		colors[pt] = c
+		delete(p.overlay, pt)
+	}
+	laid, err := placeLayer(p.bounds, colors, &Options{Bricks: p.shapes(sig, colors)}, false, nil)
+	if err != nil {
+		return err
+	}
+	laid.bricks.each(func(pos image.Point, brick *Brick) {
+		p.bricks.set(pos, brick)
+	})
+	return nil
+}
+
+// shapes returns the plain rectangular bricks of p and other, and a 1x1 brick in
+// every color of colors, to lay them again with.
+func (p *Panel) shapes(other *Panel, colors map[image.Point]Color) []*Brick {
+	seen := make(map[Brick]bool)
+	var result []*Brick
+	add := func(b Brick) {
+		if b.Mask == 0 && !b.Technic && !seen[b.canonical()] {
+			seen[b.canonical()] = true
+			result = append(result, &b)
+		}
+	}
+	for _, panel := range []*Panel{p, other} {
+		panel.bricks.each(func(_ image.Point, brick *Brick) {
+			add(*brick)
+		})
+	}
+	for _, c := range colors {
+		add(Brick{image.Point{1, 1}, c, 0, false})
+	}
+	return result
+}
+
+// contrasting returns the color, among those of p and sig, whose smallest
+// difference to the colors under the studs of stamp is the largest.
+func (p *Panel) contrasting(stamp map[image.Point]Color, sig *Panel) Color {
+	cells := p.cells()
+	under := make(map[Color]bool)
+	for pt := range stamp {
+		if pos, ok := cells.at(pt); ok {
+			under[p.bricks.at(pos).Color] = true
+		}
+	}
+	var best Color
+	bestScore := -1.0
+	consider := func(_ image.Point, brick *Brick) {
+		c := brick.Color
+		score := math.Inf(1)
+		for u := range under {
+			score = math.Min(score, deltaE(c.color, u.color))
+		}
+		if score > bestScore || score == bestScore && c.name < best.name {
+			best, bestScore = c, score
+		}
+	}
+	p.bricks.each(consider)
+	sig.bricks.each(consider)
+	return best
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"golang.org/x/image/font/basicfont"
+	"image"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	sig, err := NewTextPanel("A", basicfont.Face7x13, &Options{Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter := studColors(sig)
+	at := image.Point{2, 1}
+	for _, tc := range []struct {
+		name string
+		mode EmbedMode
+		// want maps the color of a stud of the signature to its color
+		// once stamped on red, or returns false where red stays.
+		want func(c Color) (Color, bool)
+	}{
+		{"opaque", EmbedOpaque, func(c Color) (Color, bool) { return c, true }},
+		{"mark", EmbedMark, func(c Color) (Color, bool) { return c, c == BLACK }},
+		{"contrast", EmbedContrast, func(c Color) (Color, bool) { return WHITE, c == BLACK }},
+	} {
+		p, err := NewPanel(blocks(16, 16, image.Rect(0, 0, 16, 16)), &Options{Width: 16, Bricks: BASIC_BRICKS})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Embed(sig, at, tc.mode); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := studColors(p)
+		if len(got) != 16*16 {
+			t.Errorf("%s: %d studs covered", tc.name, len(got))
+		}
+		for pt, c := range got {
+			want := BRIGHT_RED
+			if s, ok := letter[pt.Sub(at)]; ok {
+				if stamped, ok := tc.want(s); ok {
+					want = stamped
+				}
+			}
+			if c != want {
+				t.Errorf("%s: %v at %v, want %v", tc.name, c, pt, want)
+				break
+			}
+		}
+	}
+	p, err := NewPanel(blocks(16, 16, image.Rectangle{}), &Options{Width: 16, Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Embed(sig, image.Point{8, 8}, EmbedOpaque); err == nil {
+		t.Error("embedded a signature that doesn't fit")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"encoding/csv"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ExportGridCSV writes the panel as comma separated values, one row per row
+// of studs and one field per stud holding the LEGO color ID of the brick
+// covering it, or nothing where there is no brick. Overlay plates are left
+// out.
+func (p *Panel) ExportGridCSV(w io.Writer) error {
+	cells := p.cells()
+	out := csv.NewWriter(w)
+	record := make([]string, p.bounds.Dx())
+	for y := p.bounds.Min.Y; y < p.bounds.Max.Y; y++ {
+		for x := p.bounds.Min.X; x < p.bounds.Max.X; x++ {
+			record[x-p.bounds.Min.X] = ""
+			if c, ok := colorAt(p, cells, image.Point{x, y}); ok {
+				record[x-p.bounds.Min.X] = strconv.Itoa(c.id)
+			}
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ExportIndexPNG writes the panel as a paletted PNG image with one pixel per
+// stud. Index 0 is transparent, for studs without a brick, and the others
+// are the colors of the panel by increasing LEGO color ID. Overlay plates
+// are left out.
+func (p *Panel) ExportIndexPNG(w io.Writer) error {
+	seen := make(map[Color]bool)
+	var colors []Color
+	p.bricks.each(func(_ image.Point, brick *Brick) {
+		if !seen[brick.Color] {
+			seen[brick.Color] = true
+			colors = append(colors, brick.Color)
+		}
+	})
+	if len(colors) > 255 {
+		return errors.New("lego: too many colors for an index image")
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		return colors[i].id < colors[j].id
+	})
+	palette := color.Palette{color.Transparent}
+	index := make(map[Color]uint8)
+	for _, c := range colors {
+		index[c] = uint8(len(palette))
+		palette = append(palette, c.color)
+	}
+	img := image.NewPaletted(image.Rectangle{image.ZP, p.bounds.Size()}, palette)
+	p.cells().each(func(pt, pos image.Point) {
+		img.SetColorIndex(pt.X-p.bounds.Min.X, pt.Y-p.bounds.Min.Y, index[p.bricks.at(pos).Color])
+	})
+	return png.Encode(w, img)
+}
@@ -0,0 +1,85 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image"
+	"image/png"
+	"strconv"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	p, err := NewPanel(gradient(48, 32), &Options{Width: 12, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave a hole.
+	if !p.Remove(image.Point{5, 3}) {
+		t.Fatal("no brick to remove")
+	}
+	want := studColors(p)
+	size := p.bounds.Size()
+
+	var buf bytes.Buffer
+	if err := p.ExportGridCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != size.Y {
+		t.Fatalf("CSV has %d rows, want %d", len(records), size.Y)
+	}
+	for y, record := range records {
+		if len(record) != size.X {
+			t.Fatalf("CSV row %d has %d fields, want %d", y, len(record), size.X)
+		}
+		for x, field := range record {
+			wantField := ""
+			if c, ok := want[image.Point{x, y}]; ok {
+				wantField = strconv.Itoa(c.id)
+			}
+			if field != wantField {
+				t.Errorf("CSV field for stud (%d, %d) is %q, want %q", x, y, field, wantField)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := p.ExportIndexPNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, ok := decoded.(*image.Paletted)
+	if !ok {
+		t.Fatalf("index PNG decodes to %T, want *image.Paletted", decoded)
+	}
+	if img.Bounds().Size() != size {
+		t.Fatalf("index PNG is %v, want %v", img.Bounds().Size(), size)
+	}
+	colors := make(map[Color]bool)
+	for _, c := range want {
+		colors[c] = true
+	}
+	if len(img.Palette) != len(colors)+1 {
+		t.Errorf("index PNG has %d colors, want %d and transparent", len(img.Palette), len(colors))
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			i := img.ColorIndexAt(x, y)
+			c, ok := want[image.Point{x, y}]
+			if !ok && i != 0 || ok && (i == 0 || !near(img.Palette[i], c.color, 0)) {
+				t.Errorf("index PNG has %v at (%d, %d), want %v", img.Palette[i], x, y, c.name)
+			}
+		}
+	}
+	if _, _, _, a := img.Palette[0].RGBA(); a != 0 {
+		t.Error("index 0 is not transparent")
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"github.com/nfnt/resize"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Fit controls how the image is adjusted when both Width and Height are
+// given and their aspect ratio differs from the image's.
+type Fit int
+
+const (
+	// Stretch resizes the image to exactly Width x Height, distorting it.
+	Stretch Fit = iota
+	// Contain scales the image to fit inside Width x Height. The panel may
+	// be smaller than requested along one axis.
+	Contain
+	// Cover scales the image to fill Width x Height, cropping the excess
+	// evenly from both sides.
+	Cover
+	// Pad scales the image like Contain and fills the remaining area with
+	// Options.Background, so the panel is exactly Width x Height.
+	Pad
+)
+
+// Interpolation selects the filter used to resize the image.
+type Interpolation int
+
+const (
+	Lanczos3        Interpolation = iota // The default; sharp, but may ring.
+	NearestNeighbor                      // Keeps pixel art crisp.
+	Bilinear
+	Bicubic
+	MitchellNetravali
+	Lanczos2
+)
+
+func (i Interpolation) function() resize.InterpolationFunction {
+	switch i {
+	case NearestNeighbor:
+		return resize.NearestNeighbor
+	case Bilinear:
+		return resize.Bilinear
+	case Bicubic:
+		return resize.Bicubic
+	case MitchellNetravali:
+		return resize.MitchellNetravali
+	case Lanczos2:
+		return resize.Lanczos2
+	}
+	return resize.Lanczos3
+}
+
+// geometry describes how the source image maps onto the panel: which part
+// of the source is used, the size it is resized to and where it lands on
+// the panel.
+type geometry struct {
+	src    image.Rectangle
+	size   image.Point
+	offset image.Point
+	bounds image.Rectangle
+}
+
+func scaleDim(v int, scale float64) int {
+	if r := int(scale*float64(v) + 0.5); r > 0 {
+		return r
+	}
+	return 1
+}
+
+func newGeometry(src image.Rectangle, opt *Options) geometry {
+	g := geometry{src: src}
+	w, h := int(opt.Width), int(opt.Height)
+	sx := float64(w) / float64(src.Dx())
+	sy := float64(h) / float64(src.Dy())
+	switch {
+	case h == 0:
+		g.size = image.Point{w, scaleDim(src.Dy(), sx)}
+	case w == 0:
+		g.size = image.Point{scaleDim(src.Dx(), sy), h}
+	case opt.Fit == Contain || opt.Fit == Pad:
+		scale := sx
+		if sy < sx {
+			scale = sy
+		}
+		g.size = image.Point{scaleDim(src.Dx(), scale), scaleDim(src.Dy(), scale)}
+		if g.size.X > w {
+			g.size.X = w
+		}
+		if g.size.Y > h {
+			g.size.Y = h
+		}
+	case opt.Fit == Cover:
+		scale := sx
+		if sy > sx {
+			scale = sy
+		}
+		g.size = image.Point{w, h}
+		used := image.Point{scaleDim(w, 1/scale), scaleDim(h, 1/scale)}
+		if used.X > src.Dx() {
+			used.X = src.Dx()
+		}
+		if used.Y > src.Dy() {
+			used.Y = src.Dy()
+		}
+		min := src.Min.Add(src.Size().Sub(used).Div(2))
+		if opt.Focus != nil {
+			min = opt.Focus.Sub(used.Div(2))
+			min.X = clampInt(min.X, src.Min.X, src.Max.X-used.X)
+			min.Y = clampInt(min.Y, src.Min.Y, src.Max.Y-used.Y)
+		}
+		g.src = image.Rectangle{min, min.Add(used)}
+	default:
+		g.size = image.Point{w, h}
+	}
+	g.bounds = image.Rectangle{image.ZP, g.size}
+	if opt.Fit == Pad && w > 0 && h > 0 {
+		g.bounds.Max = image.Point{w, h}
+		g.offset = g.bounds.Size().Sub(g.size).Div(2)
+	}
+	return g
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// sourceRect returns the part of img to build the panel from, applying
+// Options.Crop and Options.TrimBorders.
+func sourceRect(img image.Image, opt *Options) (image.Rectangle, error) {
+	r := img.Bounds()
+	if !opt.Crop.Empty() {
+		r = opt.Crop.Intersect(r)
+		if r.Empty() {
+			return r, fmt.Errorf("lego: crop %v outside the image bounds %v", opt.Crop, img.Bounds())
+		}
+	}
+	if opt.TrimBorders {
+		r = trimBorders(img, r)
+	}
+	return r, nil
+}
+
+// similar reports whether two colors are close enough to belong to the same
+// uniform border, allowing for compression noise.
+func similar(a, b color.Color) bool {
+	const tolerance = 0x0c00
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	near := func(x, y uint32) bool {
+		return x-y < tolerance || y-x < tolerance
+	}
+	return near(ar, br) && near(ag, bg) && near(ab, bb) && near(aa, ba)
+}
+
+// trimBorders shrinks r past any rows and columns along its edges that have
+// the same color as its top-left corner.
+func trimBorders(img image.Image, r image.Rectangle) image.Rectangle {
+	orig := r
+	border := img.At(r.Min.X, r.Min.Y)
+	uniform := func(x0, y0, x1, y1 int) bool {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if !similar(img.At(x, y), border) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for r.Min.Y < r.Max.Y && uniform(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1) {
+		r.Min.Y++
+	}
+	if r.Empty() {
+		// A uniform image has nothing to trim to.
+		return orig
+	}
+	for uniform(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y) {
+		r.Max.Y--
+	}
+	for uniform(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y) {
+		r.Min.X++
+	}
+	for uniform(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y) {
+		r.Max.X--
+	}
+	return r
+}
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// cropped restricts an image that does not implement SubImage.
+type cropped struct {
+	image.Image
+	rect image.Rectangle
+}
+
+func (c *cropped) Bounds() image.Rectangle {
+	return c.rect
+}
+
+func crop(img image.Image, r image.Rectangle) image.Image {
+	if r == img.Bounds() {
+		return img
+	}
+	if s, ok := img.(subImager); ok {
+		return s.SubImage(r)
+	}
+	return &cropped{img, r.Intersect(img.Bounds())}
+}
+
+// apply crops, resizes and adjusts img, placing it on a canvas filled with
+// the background color when padding is needed.
+func (g geometry) apply(img image.Image, opt *Options) image.Image {
+	// The bands of NewPanelStream may be all padding.
+	var scaled image.Image
+	if g.size.X > 0 && g.size.Y > 0 {
+		scaled = resize.Resize(uint(g.size.X), uint(g.size.Y), crop(img, g.src),
+			opt.Interpolation.function())
+		scaled = opt.Adjust.adjust(scaled)
+	}
+	if g.bounds.Size() == g.size {
+		return scaled
+	}
+	background := opt.Background
+	if background.color == nil {
+		background = WHITE
+	}
+	canvas := image.NewNRGBA(g.bounds)
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{background.color}, image.ZP, draw.Src)
+	if scaled != nil {
+		draw.Draw(canvas, image.Rectangle{g.offset, g.offset.Add(g.size)}, scaled,
+			scaled.Bounds().Min, draw.Src)
+	}
+	return canvas
+}
+
+// project maps the used part of the source image onto another image covering
+// the same area, such as a lock mask, whose resolution may differ.
+func (g geometry) project(src image.Rectangle, other image.Rectangle) image.Rectangle {
+	conv := func(p image.Point) image.Point {
+		return image.Point{
+			other.Min.X + (p.X-src.Min.X)*other.Dx()/src.Dx(),
+			other.Min.Y + (p.Y-src.Min.Y)*other.Dy()/src.Dy(),
+		}
+	}
+	return image.Rectangle{conv(g.src.Min), conv(g.src.Max)}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// FrameSpec describes a border built around the panel, which grows by
+// Width studs on every side. The frame is made of the bricks in
+// Options.Bricks of its color, which default to BLACK.
+type FrameSpec struct {
+	Width int
+	Color Color
+	// Corners puts a 2x2 corner brick (see CORNER) at each corner of the
+	// frame, locking its sides together. They don't count towards
+	// Options.MaxInventory.
+	Corners bool
+}
+
+// framed returns the panel with the frame of opt around it, or the panel
+// itself if there is no frame.
+func (p *Panel) framed(opt *Options) (*Panel, error) {
+	spec := opt.Frame
+	if spec.Width == 0 {
+		return p, nil
+	}
+	if spec.Width < 0 {
+		return nil, errors.New("lego: negative frame width")
+	}
+	c := spec.Color
+	if c.color == nil {
+		c = BLACK
+	}
+	found := false
+	for _, brick := range opt.Bricks {
+		found = found || brick.Color == c
+	}
+	if !found {
+		return nil, fmt.Errorf("lego: no %s bricks for the frame", c.name)
+	}
+	w := spec.Width
+	size := p.bounds.Size().Add(image.Point{2 * w, 2 * w})
+	if spec.Corners && (size.X < 4 || size.Y < 4) {
+		return nil, errors.New("lego: panel too small for frame corners")
+	}
+
+	// Place the frame like a panel of its color with the picture already
+	// in the middle.
+	dst := image.NewPaletted(image.Rectangle{image.ZP, size}, color.Palette{color.Transparent, c.color})
+	inner := image.Rectangle{image.Point{w, w}, size.Sub(image.Point{w, w})}
+	ret := newPanel(dst.Bounds())
+	ret.overlay = make(map[image.Point]Color)
+	helper := newHelper(opt.Bricks, dst, ret, opt)
+	if helper.inventory != nil {
+		for brick, n := range p.CountBricks() {
+			helper.inventory[brick] -= n
+		}
+	}
+	offset := inner.Min.Sub(p.bounds.Min)
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		ret.bricks.set(pos.Add(offset), brick)
+	})
+	for pt, overlay := range p.overlay {
+		ret.overlay[pt.Add(offset)] = overlay
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if pt := (image.Point{x, y}); pt.In(inner) {
+				helper.visited.add(pt)
+			} else {
+				dst.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	if spec.Corners {
+		end := size.Sub(image.Point{2, 2})
+		corner := Brick{image.Point{2, 2}, c, CORNER, false}
+		if s, ok := opt.Substitutions[c]; ok {
+			corner.Color = s
+		}
+		for _, pos := range []image.Point{{0, 0}, {end.X, 0}, end, {0, end.Y}} {
+			brick := corner
+			ret.bricks.set(pos.Add(brick.anchor()), &brick)
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 2; x++ {
+					if brick.Covers(image.Point{x, y}) {
+						helper.visited.add(pos.Add(image.Point{x, y}))
+					}
+				}
+			}
+			corner = corner.rotate()
+		}
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if err := helper.placeBrick(image.Point{x, y}, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.copyTags(ret, func(pos image.Point) image.Point {
+		return pos.Add(offset)
+	})
+	ret.Tag(FRAME_TAG, func(pos image.Point, _ Brick) bool {
+		return p.bricks.at(pos.Sub(offset)) == nil
+	})
+	return ret, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// brickGrid holds the bricks of a panel by the position of their top-left
+// stud, in a flat slice indexed row-major over the panel's bounds, so
+// iterating over it is cheap and always in row-major order.
+type brickGrid struct {
+	bounds image.Rectangle
+	bricks []*Brick
+	n      int
+	// max is at least the size of every brick ever set, for around.
+	max image.Point
+}
+
+func newBrickGrid(bounds image.Rectangle) brickGrid {
+	return brickGrid{bounds: bounds, bricks: make([]*Brick, bounds.Dx()*bounds.Dy())}
+}
+
+func newPanel(bounds image.Rectangle) *Panel {
+	return &Panel{bricks: newBrickGrid(bounds), bounds: bounds}
+}
+
+// at returns the brick at pos, or nil if there is none.
+func (g *brickGrid) at(pos image.Point) *Brick {
+	if !pos.In(g.bounds) {
+		return nil
+	}
+	return g.bricks[(pos.Y-g.bounds.Min.Y)*g.bounds.Dx()+pos.X-g.bounds.Min.X]
+}
+
+// set puts b at pos, which must be inside the bounds. A nil b removes the
+// brick at pos.
+func (g *brickGrid) set(pos image.Point, b *Brick) {
+	i := (pos.Y-g.bounds.Min.Y)*g.bounds.Dx() + pos.X - g.bounds.Min.X
+	if g.bricks[i] != nil {
+		g.n--
+	}
+	if b != nil {
+		g.n++
+		if b.Size.X > g.max.X {
+			g.max.X = b.Size.X
+		}
+		if b.Size.Y > g.max.Y {
+			g.max.Y = b.Size.Y
+		}
+	}
+	g.bricks[i] = b
+}
+
+// around calls fn, in row-major order, for the bricks that may cover studs
+// of r: those at most the size of the largest brick away from it.
+func (g *brickGrid) around(r image.Rectangle, fn func(pos image.Point, b *Brick)) {
+	d := g.max.Sub(image.Point{1, 1})
+	r = image.Rectangle{r.Min.Sub(d), r.Max.Add(d)}.Intersect(g.bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if b := g.at(image.Point{x, y}); b != nil {
+				fn(image.Point{x, y}, b)
+			}
+		}
+	}
+}
+
+func (g *brickGrid) len() int {
+	return g.n
+}
+
+// each calls fn for every brick, in row-major order.
+func (g *brickGrid) each(fn func(pos image.Point, b *Brick)) {
+	w := g.bounds.Dx()
+	for i, b := range g.bricks {
+		if b != nil {
+			fn(image.Point{g.bounds.Min.X + i%w, g.bounds.Min.Y + i/w}, b)
+		}
+	}
+}
+
+// studSet is a set of studs within bounds.
+type studSet struct {
+	bounds image.Rectangle
+	studs  []bool
+}
+
+func newStudSet(bounds image.Rectangle) studSet {
+	return studSet{bounds: bounds, studs: make([]bool, bounds.Dx()*bounds.Dy())}
+}
+
+func (s *studSet) has(pt image.Point) bool {
+	return pt.In(s.bounds) && s.studs[(pt.Y-s.bounds.Min.Y)*s.bounds.Dx()+pt.X-s.bounds.Min.X]
+}
+
+// add puts pt, which must be inside the bounds, in the set.
+func (s *studSet) add(pt image.Point) {
+	s.studs[(pt.Y-s.bounds.Min.Y)*s.bounds.Dx()+pt.X-s.bounds.Min.X] = true
+}
+
+// cellGrid maps every stud covered by a brick to the position of the brick
+// covering it, in a flat slice over the panel's bounds like brickGrid, so
+// iterating over it is always in row-major order.
+type cellGrid struct {
+	studSet
+	pos []image.Point
+	n   int
+}
+
+// at returns the position of the brick covering pt, if any.
+func (g *cellGrid) at(pt image.Point) (image.Point, bool) {
+	if !g.has(pt) {
+		return image.ZP, false
+	}
+	return g.pos[(pt.Y-g.bounds.Min.Y)*g.bounds.Dx()+pt.X-g.bounds.Min.X], true
+}
+
+// set records that the brick at pos covers pt, which must be inside the
+// bounds.
+func (g *cellGrid) set(pt, pos image.Point) {
+	if !g.has(pt) {
+		g.n++
+	}
+	g.add(pt)
+	g.pos[(pt.Y-g.bounds.Min.Y)*g.bounds.Dx()+pt.X-g.bounds.Min.X] = pos
+}
+
+// len returns the number of covered studs.
+func (g *cellGrid) len() int {
+	return g.n
+}
+
+// each calls fn for every covered stud, with the position of the brick
+// covering it, in row-major order.
+func (g *cellGrid) each(fn func(pt, pos image.Point)) {
+	w := g.bounds.Dx()
+	for i, ok := range g.studs {
+		if ok {
+			fn(image.Point{g.bounds.Min.X + i%w, g.bounds.Min.Y + i/w}, g.pos[i])
+		}
+	}
+}
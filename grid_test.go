@@ -0,0 +1,111 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestCells(t *testing.T) {
+	p := newPanel(image.Rect(1, 1, 6, 4))
+	corner := Brick{image.Point{2, 2}, BLACK, CORNER, false}
+	for _, b := range []struct {
+		pos   image.Point
+		brick Brick
+	}{
+		{image.Point{1, 1}.Add(corner.anchor()), corner},
+		{image.Point{4, 1}, Brick{image.Point{2, 1}, WHITE, 0, false}},
+		{image.Point{3, 3}, Brick{image.Point{1, 1}, WHITE, 0, false}},
+	} {
+		if err := p.Place(b.pos, b.brick); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cells := p.cells()
+	var order []image.Point
+	cells.each(func(pt, pos image.Point) {
+		order = append(order, pt)
+		if at, ok := cells.at(pt); !ok || at != pos {
+			t.Errorf("at(%v) = %v, %v, want %v", pt, at, ok, pos)
+		}
+	})
+	var want []image.Point
+	for y := p.bounds.Min.Y; y < p.bounds.Max.Y; y++ {
+		for x := p.bounds.Min.X; x < p.bounds.Max.X; x++ {
+			pt := image.Point{x, y}
+			_, pos, ok := p.BrickAt(pt)
+			if ok {
+				want = append(want, pt)
+			}
+			if at, covered := cells.at(pt); covered != ok || ok && at != pos {
+				t.Errorf("at(%v) = %v, %v, want %v, %v", pt, at, covered, pos, ok)
+			}
+		}
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("each visits %v, want %v in row-major order", order, want)
+	}
+	if cells.len() != len(want) {
+		t.Errorf("len() = %d, want %d", cells.len(), len(want))
+	}
+}
+
+// benchSize is the size, in studs, of the panels of the benchmarks: large
+// enough that the grid dominates.
+var benchSize = image.Point{256, 192}
+
+func benchPanel(b *testing.B) *Panel {
+	p, err := NewPanel(gradient(4*benchSize.X, 4*benchSize.Y), &Options{Width: uint(benchSize.X), Bricks: ALL_BRICKS})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return p
+}
+
+func BenchmarkNewPanel(b *testing.B) {
+	img := gradient(4*benchSize.X, 4*benchSize.Y)
+	opt := &Options{Width: uint(benchSize.X), Bricks: ALL_BRICKS}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewPanel(img, opt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPlace measures placement alone, from an image already mapped to
+// brick colors.
+func BenchmarkPlace(b *testing.B) {
+	var palette color.Palette
+	mapping := make(map[color.Color]Color)
+	for _, c := range ALL_COLORS {
+		palette = append(palette, c.color)
+		mapping[c.color] = c
+	}
+	img := image.NewPaletted(image.Rectangle{image.ZP, benchSize}, palette)
+	src := gradient(benchSize.X, benchSize.Y)
+	for y := 0; y < benchSize.Y; y++ {
+		for x := 0; x < benchSize.X; x++ {
+			img.Set(x, y, src.At(x, y))
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewPanelFromPaletted(img, mapping, ALL_BRICKS); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	p := benchPanel(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Draw(8, true)
+	}
+}
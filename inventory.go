@@ -0,0 +1,28 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+// Inventory counts available bricks, keyed in canonical orientation.
+type Inventory map[Brick]int
+
+// Missing returns how many bricks of each kind the panel needs beyond what
+// is in the inventory. An empty result means the panel can be built.
+func (inv Inventory) Missing(p *Panel) map[Brick]int {
+	result := make(map[Brick]int)
+	for brick, n := range p.CountBricks() {
+		if n > inv[brick] {
+			result[brick] = n - inv[brick]
+		}
+	}
+	return result
+}
+
+func (inv Inventory) clone() Inventory {
+	if inv == nil {
+		return nil
+	}
+	result := make(Inventory, len(inv))
+	for brick, n := range inv {
+		result[brick.canonical()] += n
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"strconv"
+)
+
+// labelFace is the bitmap font labels are drawn with, scaled to size.
+var labelFace = basicfont.Face7x13
+
+// textHeight returns the height text asked to be height pixels high is
+// drawn at: a multiple of the font's own, when possible, so it stays crisp.
+func textHeight(height int) int {
+	if height >= labelFace.Height {
+		return height / labelFace.Height * labelFace.Height
+	}
+	return height
+}
+
+// textWidth returns how wide text is when drawn height pixels high.
+func textWidth(text string, height int) int {
+	return font.MeasureString(labelFace, text).Ceil() * textHeight(height) / labelFace.Height
+}
+
+// drawText writes text in c, height pixels high, with its left end at the
+// middle of its line at pt.
+func drawText(out draw.Image, text string, pt image.Point, height int, c color.Color) {
+	if text == "" || height <= 0 {
+		return
+	}
+	d := &font.Drawer{Src: image.Opaque, Face: labelFace}
+	mask := image.NewAlpha(image.Rect(0, 0, d.MeasureString(text).Ceil(), labelFace.Height))
+	d.Dst = mask
+	d.Dot = fixed.P(0, labelFace.Ascent)
+	d.DrawString(text)
+	var scaler xdraw.Scaler = xdraw.ApproxBiLinear
+	if height >= labelFace.Height {
+		scaler = xdraw.NearestNeighbor
+	}
+	height = textHeight(height)
+	r := image.Rect(0, 0, textWidth(text, height), height).Add(pt.Sub(image.Point{0, height / 2}))
+	scaled := image.NewAlpha(r)
+	scaler.Scale(scaled, r, mask, mask.Bounds(), draw.Src, nil)
+	draw.DrawMask(out, r, &image.Uniform{c}, image.ZP, scaled, r.Min, draw.Over)
+}
+
+// labelPoint returns where the label of a brick at pos goes, in pixels: the
+// center of a rectangular brick, or of the first stud of a shaped one.
+func labelPoint(pos image.Point, brick *Brick, scale int) image.Point {
+	if brick.Mask != 0 {
+		return pos.Mul(scale).Add(image.Point{scale / 2, scale / 2})
+	}
+	min := pos.Sub(brick.anchor()).Mul(scale)
+	return min.Add(brick.Size.Mul(scale).Div(2))
+}
+
+// drawLabels writes the LEGO color ID of every brick on it, in black or
+// white, whichever reads better.
+func (p *Panel) drawLabels(out draw.Image, scale int) {
+	height := scale * 2 / 5
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		text := strconv.Itoa(brick.Color.id)
+		at := labelPoint(pos, brick, scale)
+		at.X -= textWidth(text, height) / 2
+		drawText(out, text, at, height, contrast(brick.Color.color))
+	})
+}
+
+// drawHighContrast draws a thick black line along the edges of every brick.
+func (p *Panel) drawHighContrast(out draw.Image, scale int) {
+	w := scale / 16
+	if w < 1 {
+		w = 1
+	}
+	black := &image.Uniform{color.Black}
+	cells := p.cells()
+	cells.each(func(pt, pos image.Point) {
+		cell := image.Rectangle{pt.Mul(scale), pt.Add(image.Point{1, 1}).Mul(scale)}
+		sides := []struct {
+			d image.Point
+			r image.Rectangle
+		}{
+			{image.Point{-1, 0}, image.Rect(cell.Min.X, cell.Min.Y, cell.Min.X+w, cell.Max.Y)},
+			{image.Point{1, 0}, image.Rect(cell.Max.X-w, cell.Min.Y, cell.Max.X, cell.Max.Y)},
+			{image.Point{0, -1}, image.Rect(cell.Min.X, cell.Min.Y, cell.Max.X, cell.Min.Y+w)},
+			{image.Point{0, 1}, image.Rect(cell.Min.X, cell.Max.Y-w, cell.Max.X, cell.Max.Y)},
+		}
+		for _, side := range sides {
+			if other, ok := cells.at(pt.Add(side.d)); !ok || other != pos {
+				draw.Draw(out, side.r, black, image.ZP, draw.Src)
+			}
+		}
+	})
+}
+
+// DrawLegend renders the key to the labels of DrawOptions.LabelBricks: one
+// row per brick color in the panel, by name, with a swatch of scale pixels
+// holding its label.
+func (p *Panel) DrawLegend(scale int) image.Image {
+	var colors []Color
+	seen := make(map[Color]bool)
+	p.bricks.each(func(_ image.Point, brick *Brick) {
+		if !seen[brick.Color] {
+			seen[brick.Color] = true
+			colors = append(colors, brick.Color)
+		}
+	})
+	sort.Slice(colors, func(i, j int) bool { return colors[i].name < colors[j].name })
+
+	height, gap := minInt(scale*2/3, labelFace.Height), scale/4
+	width := 0
+	for _, c := range colors {
+		if w := textWidth(c.name, height); w > width {
+			width = w
+		}
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, gap+scale+gap+width+gap, gap+len(colors)*(scale+gap)))
+	draw.Draw(out, out.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
+	for i, c := range colors {
+		swatch := image.Rect(0, 0, scale, scale).Add(image.Point{gap, gap + i*(scale+gap)})
+		draw.Draw(out, swatch, &image.Uniform{color.Black}, image.ZP, draw.Src)
+		draw.Draw(out, swatch.Inset(1), &image.Uniform{c.color}, image.ZP, draw.Src)
+		text := strconv.Itoa(c.id)
+		mid := swatch.Min.Y + scale/2
+		drawText(out, text, image.Point{swatch.Min.X + (scale-textWidth(text, height))/2, mid}, height, contrast(c.color))
+		drawText(out, c.name, image.Point{swatch.Max.X + gap, mid}, height, color.Black)
+	}
+	return out
+}
+
+// drawGrid draws a line across the panel every n studs, in both directions.
+func (p *Panel) drawGrid(out draw.Image, n, scale int) {
+	w := scale / 12
+	if w < 1 {
+		w = 1
+	}
+	line := &image.Uniform{color.NRGBA{255, 0, 0, 160}}
+	size := p.bounds.Size().Mul(scale)
+	for x := n; x < p.bounds.Dx(); x += n {
+		draw.Draw(out, image.Rect(x*scale-w/2, 0, x*scale-w/2+w, size.Y), line, image.ZP, draw.Over)
+	}
+	for y := n; y < p.bounds.Dy(); y += n {
+		draw.Draw(out, image.Rect(0, y*scale-w/2, size.X, y*scale-w/2+w), line, image.ZP, draw.Over)
+	}
+}
+
+// withRulers returns img, a rendered panel, with the column numbers along
+// its top and the row numbers along its left side, counting from 1.
+func (p *Panel) withRulers(img image.Image, scale int) *image.NRGBA {
+	height := scale * 2 / 5
+	if height < 8 {
+		height = 8
+	}
+	size := p.bounds.Size()
+	margin := image.Point{textWidth(strconv.Itoa(size.Y), height) + height, 2 * height}
+	out := image.NewNRGBA(image.Rectangle{image.ZP, img.Bounds().Size().Add(margin)})
+	draw.Draw(out, out.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
+	draw.Draw(out, img.Bounds().Add(margin), img, img.Bounds().Min, draw.Src)
+	step := rulerStep(scale, textWidth(strconv.Itoa(size.X), height)+height/2)
+	for x := 0; x < size.X; x++ {
+		if x == 0 || (x+1)%step == 0 {
+			text := strconv.Itoa(x + 1)
+			at := image.Point{margin.X + x*scale + (scale-textWidth(text, height))/2, margin.Y / 2}
+			drawText(out, text, at, height, color.Black)
+		}
+	}
+	step = rulerStep(scale, height+height/4)
+	for y := 0; y < size.Y; y++ {
+		if y == 0 || (y+1)%step == 0 {
+			text := strconv.Itoa(y + 1)
+			at := image.Point{margin.X - height/2 - textWidth(text, height), margin.Y + y*scale + scale/2}
+			drawText(out, text, at, height, color.Black)
+		}
+	}
+	return out
+}
+
+// rulerStep returns how many studs apart to number a ruler with room pixels
+// per stud and numbers need pixels wide: 1, 2, 5, 10, 20 and so on.
+func rulerStep(room, need int) int {
+	if room <= 0 {
+		return 1
+	}
+	for step := 1; ; step *= 10 {
+		for _, s := range []int{step, 2 * step, 5 * step} {
+			if s*room >= need {
+				return s
+			}
+		}
+	}
+}
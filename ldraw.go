@@ -0,0 +1,39 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// LDraw units: a stud is 20 wide, a plate 8 high and a brick 24.
+const (
+	ldrawStud  = 20
+	ldrawPlate = 8
+	ldrawBrick = 24
+)
+
+// ldrawHeader starts an LDraw model file.
+func ldrawHeader(out *errWriter, name string) {
+	out.printf("0 %s\n", name)
+	out.printf("0 Name: %s.ldr\n", name)
+	out.printf("0 Author: github.com/mrbubble/lego\n")
+}
+
+// ldrawPart writes a part lying flat with its top-left stud at pos, seen
+// from above, and the top of its body at height y (negative is up). Panel
+// columns run along the LDraw X axis and rows along Z.
+func ldrawPart(out *errWriter, part string, brick Brick, pos image.Point, y int) {
+	x := float64(pos.X)*ldrawStud + float64(brick.Size.X)*ldrawStud/2
+	z := float64(pos.Y)*ldrawStud + float64(brick.Size.Y)*ldrawStud/2
+	// Parts are modelled along X; turn those standing along the rows.
+	rotation := "1 0 0 0 1 0 0 0 1"
+	if brick.Size.X < brick.Size.Y {
+		rotation = "0 0 1 0 1 0 -1 0 0"
+	}
+	out.printf("1 %d %g %d %g %s %s.dat\n", brick.Color.lDraw, x, y, z, rotation, part)
+}
+
+// ldrawStep separates building steps.
+func ldrawStep(out *errWriter) {
+	out.printf("0 STEP\n")
+}
@@ -2,16 +2,23 @@
 package lego
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/nfnt/resize"
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
+	"math/rand"
+	"sort"
 )
 
 type Color struct {
-	name  string
-	color color.Color
+	name      string
+	color     color.Color
+	id        int // LEGO color ID.
+	brickLink int
+	lDraw     int
 }
 
 var (
@@ -19,21 +26,29 @@ var (
 	//   http://www.peeron.com/cgi-bin/invcgis/colorguide.cgi
 	// Selected colors from http://shop.lego.com that are available for
 	// 1x1 bricks, so any images are doable.
-	WHITE                  = Color{"White (#1)", color.NRGBA{242, 243, 242, 255}}
-	BRIGHT_RED             = Color{"Bright red (#21)", color.NRGBA{196, 40, 27, 255}}
-	BRIGHT_BLUE            = Color{"Bright blue (#23)", color.NRGBA{13, 105, 171, 255}}
-	BLACK                  = Color{"Black (#26)", color.NRGBA{27, 42, 52, 255}}
-	DARK_GREEN             = Color{"Dark green (#28)", color.NRGBA{40, 127, 70, 255}}
-	BRIGHT_YELLOW          = Color{"Bright yellow (#24)", color.NRGBA{245, 205, 47, 255}}
-	BRICK_YELLOW           = Color{"Brick yellow (#5)", color.NRGBA{215, 197, 153, 255}}
-	BRIGHT_ORANGE          = Color{"Bright orange (#106)", color.NRGBA{218, 133, 64, 255}}
-	MEDIUM_BLUE            = Color{"Medium blue (#102)", color.NRGBA{110, 153, 201, 255}}
-	DARK_STONE_GREY        = Color{"Dark stone grey (#199)", color.NRGBA{99, 95, 97, 255}}
-	REDDISH_BROWN          = Color{"Reddish brown (#192)", color.NRGBA{105, 64, 39, 255}}
-	MEDIUM_STONE_GREY      = Color{"Medium stone grey (#194)", color.NRGBA{163, 162, 164, 255}}
-	BRIGHT_YELLOWISH_GREEN = Color{"Bright yellowish green (#119)", color.NRGBA{164, 189, 70, 255}}
-	LIGHT_PURPLE           = Color{"Light purple (#222)", color.NRGBA{228, 173, 200, 255}}
-	BRIGHT_REDDISH_VIOLET  = Color{"Bright reddish violet (#124)", color.NRGBA{146, 57, 120, 255}}
+	// BrickLink and LDraw IDs from http://www.bricklink.com/catalogColors.asp
+	// and http://www.ldraw.org/article/547.html.
+	WHITE                  = Color{"White (#1)", color.NRGBA{242, 243, 242, 255}, 1, 1, 15}
+	BRIGHT_RED             = Color{"Bright red (#21)", color.NRGBA{196, 40, 27, 255}, 21, 5, 4}
+	BRIGHT_BLUE            = Color{"Bright blue (#23)", color.NRGBA{13, 105, 171, 255}, 23, 7, 1}
+	BLACK                  = Color{"Black (#26)", color.NRGBA{27, 42, 52, 255}, 26, 11, 0}
+	DARK_GREEN             = Color{"Dark green (#28)", color.NRGBA{40, 127, 70, 255}, 28, 6, 2}
+	BRIGHT_YELLOW          = Color{"Bright yellow (#24)", color.NRGBA{245, 205, 47, 255}, 24, 3, 14}
+	BRICK_YELLOW           = Color{"Brick yellow (#5)", color.NRGBA{215, 197, 153, 255}, 5, 2, 19}
+	BRIGHT_ORANGE          = Color{"Bright orange (#106)", color.NRGBA{218, 133, 64, 255}, 106, 4, 25}
+	MEDIUM_BLUE            = Color{"Medium blue (#102)", color.NRGBA{110, 153, 201, 255}, 102, 42, 73}
+	DARK_STONE_GREY        = Color{"Dark stone grey (#199)", color.NRGBA{99, 95, 97, 255}, 199, 85, 72}
+	REDDISH_BROWN          = Color{"Reddish brown (#192)", color.NRGBA{105, 64, 39, 255}, 192, 88, 70}
+	MEDIUM_STONE_GREY      = Color{"Medium stone grey (#194)", color.NRGBA{163, 162, 164, 255}, 194, 86, 71}
+	BRIGHT_YELLOWISH_GREEN = Color{"Bright yellowish green (#119)", color.NRGBA{164, 189, 70, 255}, 119, 34, 27}
+	LIGHT_PURPLE           = Color{"Light purple (#222)", color.NRGBA{228, 173, 200, 255}, 222, 104, 29}
+	BRIGHT_REDDISH_VIOLET  = Color{"Bright reddish violet (#124)", color.NRGBA{146, 57, 120, 255}, 124, 71, 26}
+
+	ALL_COLORS = []Color{
+		WHITE, BRIGHT_RED, BRIGHT_BLUE, BLACK, DARK_GREEN, BRIGHT_YELLOW,
+		BRICK_YELLOW, BRIGHT_ORANGE, MEDIUM_BLUE, DARK_STONE_GREY, REDDISH_BROWN,
+		MEDIUM_STONE_GREY, BRIGHT_YELLOWISH_GREEN, LIGHT_PURPLE, BRIGHT_REDDISH_VIOLET,
+	}
 )
 
 func (c *Color) Name() string {
@@ -44,88 +59,320 @@ func (c *Color) Color() color.Color {
 	return c.color
 }
 
+// ID returns the LEGO color ID.
+func (c *Color) ID() int {
+	return c.id
+}
+
+func (c *Color) BrickLinkID() int {
+	return c.brickLink
+}
+
+func (c *Color) LDrawID() int {
+	return c.lDraw
+}
+
+// Shapes returns the brick sizes, in canonical orientation, in which the
+// color is available.
+func (c *Color) Shapes() []image.Point {
+	return availability[c.id]
+}
+
+// Available reports whether bricks of the given size, in either orientation,
+// are made in this color.
+func (c *Color) Available(shape image.Point) bool {
+	shape = Brick{shape, *c, 0, false}.canonical().Size
+	for _, s := range availability[c.id] {
+		if s == shape {
+			return true
+		}
+	}
+	return false
+}
+
 type Brick struct {
 	Size  image.Point
 	Color Color
+	// Mask, if not zero, tells which studs within Size the brick covers, bit
+	// y*Size.X+x being set for the stud at (x, y). Zero covers them all.
+	// In a panel, such bricks are positioned by the first stud they cover.
+	// The placer only uses rectangular bricks; see CORNER and FrameSpec.
+	Mask uint64
+	// Technic marks a 1xN Technic brick, with holes through its side for
+	// pins or axles, such as those MountingPlan adds. The placer never uses
+	// them.
+	Technic bool
 }
 
+// generateBricks returns bricks of the given shapes in each color, skipping
+// those that are not made.
 func generateBricks(shapes []image.Point, colors ...Color) []*Brick {
 	var result []*Brick
 	for _, color := range colors {
 		for _, shape := range shapes {
-			result = append(result, &Brick{shape, color})
+			if color.Available(shape) {
+				result = append(result, &Brick{shape, color, 0, false})
+			}
 		}
 	}
 	return result
 }
 
+// AvailableBricks returns bricks of the given shape in every color it is
+// made in.
+func AvailableBricks(shape image.Point) []*Brick {
+	return generateBricks([]image.Point{shape}, ALL_COLORS...)
+}
+
 var (
 	basicShapes = []image.Point{
 		{1, 1}, {1, 2}, {1, 4}, {2, 2}, {2, 4},
 	}
+	extendedShapes = []image.Point{
+		{1, 1}, {1, 2}, {1, 3}, {1, 4}, {1, 6}, {1, 8}, {2, 2}, {2, 3}, {2, 4}, {2, 6}, {2, 8},
+	}
+	// Shapes each color is available in, by LEGO color ID.
+	availability = map[int][]image.Point{
+		1:   extendedShapes,
+		21:  extendedShapes,
+		23:  extendedShapes,
+		26:  extendedShapes,
+		28:  extendedShapes,
+		24:  extendedShapes,
+		5:   extendedShapes,
+		106: extendedShapes,
+		102: {{1, 1}, {1, 2}, {1, 4}},
+		199: basicShapes,
+		192: basicShapes,
+		194: basicShapes,
+		119: basicShapes,
+		222: basicShapes,
+		124: {{1, 1}, {1, 2}},
+	}
 	BASIC_BRICKS = generateBricks(basicShapes, WHITE, BRIGHT_RED, BRIGHT_BLUE,
 		BLACK, DARK_GREEN, BRIGHT_YELLOW, BRICK_YELLOW, BRIGHT_ORANGE,
 	)
-	ADVANCED_BRICKS = append(
-		generateBricks(basicShapes, DARK_STONE_GREY, REDDISH_BROWN,
-			MEDIUM_STONE_GREY, BRIGHT_YELLOWISH_GREEN, LIGHT_PURPLE),
-		append(generateBricks([]image.Point{{1, 1}, {1, 2}, {1, 4}}, MEDIUM_BLUE),
-			generateBricks([]image.Point{{1, 1}, {1, 2}}, BRIGHT_REDDISH_VIOLET)...,
-		)...,
+	ADVANCED_BRICKS = generateBricks(basicShapes, DARK_STONE_GREY, REDDISH_BROWN,
+		MEDIUM_STONE_GREY, BRIGHT_YELLOWISH_GREEN, LIGHT_PURPLE, MEDIUM_BLUE,
+		BRIGHT_REDDISH_VIOLET,
 	)
 	ALL_BRICKS = append(BASIC_BRICKS, ADVANCED_BRICKS...)
+	// GREYSCALE_BRICKS are the basic shapes in white, black and greys.
+	GREYSCALE_BRICKS = generateBricks(basicShapes, WHITE, MEDIUM_STONE_GREY,
+		DARK_STONE_GREY, BLACK,
+	)
+	// EXTENDED_BRICKS adds the longer 1x3, 1x6, 1x8, 2x3, 2x6 and 2x8
+	// bricks to ALL_BRICKS, in the colors they are made in.
+	EXTENDED_BRICKS = generateBricks(extendedShapes, ALL_COLORS...)
 )
 
 func (b Brick) String() string {
-	return fmt.Sprintf("%dx%d %s", b.Size.X, b.Size.Y, b.Color.name)
+	return fmt.Sprintf("%s %s", b.sizeName(), b.Color.name)
 }
 
 func (b Brick) canonical() Brick {
+	if b.Mask != 0 {
+		// Take the rotation with the narrowest size and the lowest mask.
+		best, r := b, b
+		for i := 0; i < 3; i++ {
+			r = r.rotate()
+			if r.Size.X < best.Size.X || r.Size.X == best.Size.X && r.Mask < best.Mask {
+				best = r
+			}
+		}
+		return best
+	}
 	if b.Size.X <= b.Size.Y {
 		return b
 	}
-	return Brick{image.Point{b.Size.Y, b.Size.X}, b.Color}
+	return Brick{image.Point{b.Size.Y, b.Size.X}, b.Color, 0, b.Technic}
 }
 
+// Panel is a picture made of bricks. Everything that walks over its bricks,
+// from ForEach and Draw to the exports and instructions, does so in
+// row-major order of their top-left studs, and the overlay plates likewise
+// by stud, so the same panel always gives the same output.
 type Panel struct {
-	bricks map[image.Point]*Brick
-	bounds image.Rectangle
+	bricks  brickGrid
+	bounds  image.Rectangle
+	overlay map[image.Point]Color
+	// round is set when every brick is a 1x1 round plate, as in LEGO Art.
+	round bool
+	// tags holds the tags of the bricks by position, sorted.
+	tags map[image.Point][]string
+	// state is set on panels built by a Builder, for Rebuild.
+	state *buildState
 }
 
 type Options struct {
-	Width  uint
+	// Width and Height of the panel, in studs. If only one of them is set,
+	// the other is derived from the image's aspect ratio; if both are, Fit
+	// decides how the image is adjusted to them.
+	Width      uint
+	Height     uint
+	Fit        Fit
+	Background Color // Used by Pad and NewTextPanel; defaults to WHITE.
+	Foreground Color // Used by NewTextPanel; defaults to BLACK.
+	// Crop, if not empty, restricts the panel to this part of the image.
+	Crop image.Rectangle
+	// TrimBorders removes uniform borders around the (cropped) image.
+	TrimBorders bool
+	// Focus, if set, centers the crop made by Cover on this point of the
+	// image, such as a face, instead of on the middle of the image.
+	Focus         *image.Point
+	Interpolation Interpolation
+	// Adjust is applied after resizing, before mapping to brick colors.
+	Adjust Adjustments
+
 	Bricks []*Brick
 	Dither bool
+	// Importance, if set, is a grayscale mask covering the same area as the
+	// image that tones down dithering where it is bright, keeping details
+	// such as faces crisp, while darker areas absorb the noise and may take
+	// a neighbor's color within IMPORTANCE_TOLERANCE. Only used with
+	// Dither. See Saliency.
+	Importance image.Image
+	Lock       *PaletteLock
+	// MaxInventory, if set, limits how many bricks of each kind are used.
+	// Smaller bricks are used when larger ones run out.
+	MaxInventory Inventory
+	// BrickWeights biases which bricks are tried first: bricks are tried by
+	// decreasing area times weight, and weights default to 1. Weights only
+	// change preferences; any brick still gets used where nothing else fits.
+	BrickWeights map[Brick]float64
+	// Overlays, if not empty, enables the experimental two-layer mode:
+	// translucent 1x1 round plates of these colors may be placed over the
+	// bricks to approximate colors missing from the palette. See
+	// Panel.Overlay.
+	Overlays []Color
+	// Canvas, if set, is a mask covering the same area as the image that
+	// shapes the panel, such as a circle or a silhouette: studs where it is
+	// dark or transparent get no bricks, and are left out of drawings and
+	// exports. A Frame still goes around the whole rectangle.
+	Canvas image.Image
+	// Frame, if its Width is set, adds a border around the panel. Its
+	// bricks are tagged FRAME_TAG.
+	Frame FrameSpec
+	// TagMasks tags the bricks over the bright parts of masks covering the
+	// same area as the image, such as "face" over a face; see Panel.Tag.
+	// NewPanelStream ignores them.
+	TagMasks map[string]image.Image
+	// Style applies an artistic treatment while mapping the image to brick
+	// colors.
+	Style StyleSpec
+	// Substitutions replaces colors, such as those out of stock, once the
+	// image is mapped to the palette: studs of a key's color get bricks of
+	// its value's color instead, in the shapes available in either.
+	// Substitutions are not chained.
+	Substitutions map[Color]Color
+	// LUTBits, from 1 to 7, speeds up mapping the image to the palette
+	// without Dither, Overlays or a Style with a lookup table of 1<<LUTBits
+	// cells per channel, built once per Builder, or per panel with
+	// NewPanel. Around 5 suits most palettes; higher values take longer to
+	// build and leave fewer colors to look up in full.
+	LUTBits int
+	// Placer, if set, lays the bricks instead of the greedy algorithm;
+	// MaxInventory, BrickWeights and Seed are then up to it.
+	Placer Placer
+	// Seed, if not zero, randomizes the choice among equally good bricks so
+	// large areas of a single color don't repeat the same pattern. Panels
+	// built with the same seed are identical.
+	Seed int64
 }
 
 type helper struct {
-	visited map[image.Point]bool
-	panel   *Panel
-	bricks  map[Brick]bool
-	img     image.Image
+	visited   studSet
+	panel     *Panel
+	bricks    map[Brick]bool
+	img       image.Image
+	inventory Inventory
+	weights   map[Brick]float64
+	ordered   map[Color][]Brick
+	rand      *rand.Rand
+	// substitutions replaces the colors passed to placeBrick.
+	substitutions map[Color]Color
 }
 
-func newHelper(bricks []*Brick, img image.Image, p *Panel) *helper {
+func newHelper(bricks []*Brick, img image.Image, p *Panel, opt *Options) *helper {
 	ret := &helper{
-		visited: make(map[image.Point]bool),
-		panel:   p,
-		bricks:  make(map[Brick]bool),
-		img:     img,
+		visited:       newStudSet(img.Bounds()),
+		panel:         p,
+		bricks:        make(map[Brick]bool),
+		img:           img,
+		inventory:     opt.MaxInventory.clone(),
+		weights:       opt.BrickWeights,
+		ordered:       make(map[Color][]Brick),
+		substitutions: opt.Substitutions,
+	}
+	if opt.Seed != 0 {
+		ret.rand = rand.New(rand.NewSource(opt.Seed))
 	}
 	for _, brick := range bricks {
-		ret.bricks[*brick] = true
+		b := *brick
+		if c, ok := opt.Substitutions[b.Color]; ok {
+			b.Color = c
+		}
+		ret.bricks[b.canonical()] = true
 	}
 	return ret
 }
 
-func (h *helper) fit(p image.Point, brick Brick) bool {
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (h *helper) score(brick Brick) float64 {
+	weight, ok := h.weights[brick.canonical()]
+	if !ok {
+		weight = 1
+	}
+	return float64(brick.Size.X*brick.Size.Y) * weight
+}
+
+// candidates returns the bricks, in both orientations, to try for a color,
+// best first.
+func (h *helper) candidates(color Color) []Brick {
+	if result, ok := h.ordered[color]; ok {
+		return result
+	}
+	var result []Brick
+	for brick := range h.bricks {
+		if brick.Color != color || brick.Mask != 0 || brick.Technic {
+			continue
+		}
+		result = append(result, brick)
+		if brick.Size.X != brick.Size.Y {
+			result = append(result, Brick{image.Point{brick.Size.Y, brick.Size.X}, color, 0, false})
+		}
+	}
+	// Among equally good bricks, squarer ones go first, then the narrower
+	// orientation.
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if sa, sb := h.score(a), h.score(b); sa != sb {
+			return sa > sb
+		}
+		if ma, mb := minInt(a.Size.X, a.Size.Y), minInt(b.Size.X, b.Size.Y); ma != mb {
+			return ma > mb
+		}
+		return a.Size.X < b.Size.X
+	})
+	h.ordered[color] = result
+	return result
+}
+
+// fit reports whether brick fits at p over studs of color c in h.img.
+func (h *helper) fit(p image.Point, brick Brick, c color.Color) bool {
 	for y := 0; y < brick.Size.Y; y++ {
 		for x := 0; x < brick.Size.X; x++ {
 			pt := p.Add(image.Point{x, y})
-			if h.visited[pt] {
+			if !pt.In(h.img.Bounds()) || h.visited.has(pt) {
 				return false
 			}
-			if h.img.At(pt.X, pt.Y) != brick.Color.color {
+			if h.img.At(pt.X, pt.Y) != c {
 				return false
 			}
 		}
@@ -133,86 +380,429 @@ func (h *helper) fit(p image.Point, brick Brick) bool {
 	return true
 }
 
-func (h *helper) placeBrick(p image.Point, color Color) {
-	if h.visited[p] {
-		return
+func (h *helper) placeBrick(p image.Point, color Color) error {
+	if h.visited.has(p) {
+		return nil
 	}
-	for i := range basicShapes {
-		shape := basicShapes[len(basicShapes)-1-i]
-		brick := Brick{shape, color}
-		if !h.bricks[brick] {
-			continue
-		}
-		if !h.fit(p, brick) {
-			if shape.X == shape.Y {
+	want := color.color
+	if c, ok := h.substitutions[color]; ok {
+		color = c
+	}
+	candidates := h.candidates(color)
+	for i := 0; i < len(candidates); {
+		// Collect the fitting bricks among those as good as candidates[i].
+		var fits []Brick
+		score := h.score(candidates[i])
+		for ; i < len(candidates) && h.score(candidates[i]) == score; i++ {
+			brick := candidates[i]
+			if h.inventory != nil && h.inventory[brick.canonical()] <= 0 {
 				continue
 			}
-			brick = Brick{image.Point{shape.Y, shape.X}, color}
-			if !h.fit(p, brick) {
-				continue
+			if h.fit(p, brick, want) {
+				fits = append(fits, brick)
 			}
 		}
+		if len(fits) == 0 {
+			continue
+		}
+		brick := fits[0]
+		if h.rand != nil {
+			brick = fits[h.rand.Intn(len(fits))]
+		}
 		for y := 0; y < brick.Size.Y; y++ {
 			for x := 0; x < brick.Size.X; x++ {
-				h.visited[p.Add(image.Point{x, y})] = true
+				h.visited.add(p.Add(image.Point{x, y}))
 			}
 		}
-		h.panel.bricks[p] = &brick
-		return
+		h.panel.bricks.set(p, &brick)
+		if h.inventory != nil {
+			h.inventory[brick.canonical()]--
+		}
+		return nil
+	}
+	if h.inventory != nil {
+		return fmt.Errorf("lego: no %s brick left to fill %v", color.name, p)
 	}
-	panic("Impossible fit")
+	return fmt.Errorf("lego: no %s brick fits at %v; is there a 1x1 brick in this color?", color.name, p)
 }
 
-func NewPanel(img image.Image, opt *Options) *Panel {
-	scale := float64(opt.Width) / float64(img.Bounds().Dx())
-	height := uint(scale * float64(img.Bounds().Dy()))
+var (
+	// MAX_PANEL_SIDE and MAX_PANEL_AREA limit the size of panels, in studs
+	// and frame included, so that options from untrusted sources can't
+	// exhaust memory.
+	MAX_PANEL_SIDE = 4096
+	MAX_PANEL_AREA = 1 << 20
+)
 
-	var palette color.Palette
-	m := make(map[color.Color]Color)
-	for _, brick := range opt.Bricks {
-		if _, ok := m[brick.Color.color]; !ok {
-			m[brick.Color.color] = brick.Color
-			palette = append(palette, brick.Color.color)
+// checkSize returns an error if a panel of the given size, before adding the
+// frame of opt, would be too large.
+func checkSize(size image.Point, opt *Options) error {
+	w, h := size.X+2*opt.Frame.Width, size.Y+2*opt.Frame.Width
+	if size.X < 0 || size.Y < 0 || opt.Frame.Width < 0 || opt.Frame.Width > MAX_PANEL_SIDE {
+		return fmt.Errorf("lego: invalid panel size %v", size)
+	}
+	if w > MAX_PANEL_SIDE || h > MAX_PANEL_SIDE || w*h > MAX_PANEL_AREA {
+		return fmt.Errorf("lego: a %dx%d panel is too large", w, h)
+	}
+	return nil
+}
+
+// Validate checks the options, without an image, for what NewPanel would
+// reject up front: a missing size, a panel over MAX_PANEL_SIDE or
+// MAX_PANEL_AREA, invalid bricks, more than 256 colors, and values that are
+// not finite numbers. The size derived from the image's aspect ratio is checked by
+// NewPanel.
+func (opt *Options) Validate() error {
+	if opt.Width == 0 && opt.Height == 0 {
+		return errors.New("lego: Width or Height must be set")
+	}
+	if opt.Width > uint(MAX_PANEL_SIDE) || opt.Height > uint(MAX_PANEL_SIDE) {
+		return fmt.Errorf("lego: panels are limited to %d studs on a side", MAX_PANEL_SIDE)
+	}
+	// The side derived from the image is at least 1.
+	size := image.Point{int(opt.Width), int(opt.Height)}
+	if size.X == 0 {
+		size.X = 1
+	}
+	if size.Y == 0 {
+		size.Y = 1
+	}
+	if err := checkSize(size, opt); err != nil {
+		return err
+	}
+	a := opt.Adjust
+	for _, v := range []float64{a.Brightness, a.Contrast, a.Saturation, a.Gamma, a.Sharpen} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("lego: adjustments must be finite")
+		}
+	}
+	for brick, w := range opt.BrickWeights {
+		if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+			return fmt.Errorf("lego: invalid weight %g for %v", w, brick)
 		}
 	}
+	if opt.LUTBits < 0 || opt.LUTBits > 7 {
+		return fmt.Errorf("lego: LUTBits must be between 0 and 7, not %d", opt.LUTBits)
+	}
+	if opt.Lock != nil && opt.Lock.Mask == nil {
+		return errors.New("lego: palette lock without a mask")
+	}
+	return validateBricks(opt.Bricks)
+}
 
-	src := resize.Resize(opt.Width, height, img, resize.Lanczos3)
-	dst := image.NewPaletted(src.Bounds(), palette)
-	if opt.Dither {
+func validateBricks(bricks []*Brick) error {
+	if len(bricks) == 0 {
+		return errors.New("lego: no bricks to build with")
+	}
+	colors := make(map[Color]bool)
+	for _, brick := range bricks {
+		if brick == nil || brick.Size.X <= 0 || brick.Size.Y <= 0 || brick.Color.color == nil ||
+			brick.Size.X > MAX_PANEL_SIDE || brick.Size.Y > MAX_PANEL_SIDE ||
+			brick.Mask != 0 && brick.Size.X*brick.Size.Y > 64 {
+			return fmt.Errorf("lego: invalid brick %v", brick)
+		}
+		colors[brick.Color] = true
+	}
+	if len(colors) > 256 {
+		return fmt.Errorf("lego: %d brick colors, more than 256", len(colors))
+	}
+	return nil
+}
+
+// NewPanel builds a panel reproducing img with the given options.
+func NewPanel(img image.Image, opt *Options) (*Panel, error) {
+	return NewPanelContext(context.Background(), img, opt, nil)
+}
+
+// NewPanelContext is like NewPanel, but stops early with ctx.Err() when ctx
+// is done. If progress is not nil, it is called after every row of the panel
+// with the number of studs covered so far and the total.
+func NewPanelContext(ctx context.Context, img image.Image, opt *Options, progress func(done, total int)) (*Panel, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	return build(ctx, img, opt, nil, progress)
+}
+
+// build is NewPanelContext for valid options, sharing what it can through
+// cache unless it is nil.
+func build(ctx context.Context, img image.Image, opt *Options, cache *buildCache, progress func(done, total int)) (*Panel, error) {
+	r, err := resizeImage(img, opt)
+	if err != nil {
+		return nil, err
+	}
+	dst, m, overlay, err := r.quantize(opt, cache)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ret, err := place(ctx, dst, m, opt, cache, progress)
+	if err != nil {
+		return nil, err
+	}
+	ret.overlay = overlay
+	r.applyTagMasks(ret, opt.TagMasks)
+	return ret.framed(opt)
+}
+
+// resized is an image cropped, resized and adjusted to one pixel per stud,
+// with the bounds of the original image and the geometry that did it.
+type resized struct {
+	bounds image.Rectangle
+	g      geometry
+	src    image.Image
+}
+
+// resizeImage crops, resizes and adjusts img to one pixel per stud, the
+// first step of building a panel; (*resized).quantize does the rest.
+func resizeImage(img image.Image, opt *Options) (*resized, error) {
+	if img.Bounds().Empty() {
+		return nil, errors.New("lego: empty image")
+	}
+	r, err := sourceRect(img, opt)
+	if err != nil {
+		return nil, err
+	}
+	g := newGeometry(r, opt)
+	if err := checkSize(g.bounds.Size(), opt); err != nil {
+		return nil, err
+	}
+	return &resized{img.Bounds(), g, g.apply(img, opt)}, nil
+}
+
+// quantize maps the resized image to brick colors. It returns the result,
+// the brick color of every palette color and, if Options.Overlays is set,
+// where overlays go. The palette comes from cache unless it is nil.
+func (r *resized) quantize(opt *Options, cache *buildCache) (*image.Paletted, map[color.Color]Color, map[image.Point]Color, error) {
+	if cache == nil {
+		cache = newBuildCache(opt)
+	}
+	palette, m := cache.palette, cache.m
+	g := r.g
+	palette, src, err := opt.Style.apply(palette, r.src, opt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	quantized := palette
+	if len(opt.Overlays) > 0 {
+		if quantized, err = withOverlays(palette, opt.Overlays); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	dst := image.NewPaletted(src.Bounds(), quantized)
+	if opt.Dither && opt.Importance != nil {
+		ditherImportance(dst, src, importanceMap(opt.Importance, g, r.bounds, dst.Bounds()))
+	} else if opt.Dither {
 		draw.FloydSteinberg.Draw(dst, dst.Bounds(), src, src.Bounds().Min)
+	} else if (cache.nearest != nil || cache.lut != nil) && opt.Style.Kind == Photo && len(opt.Overlays) == 0 {
+		cache.draw(dst, src)
 	} else {
 		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
 	}
-	ret := &Panel{make(map[image.Point]*Brick), dst.Bounds()}
-	helper := newHelper(opt.Bricks, dst, ret)
+	if opt.Lock != nil {
+		if err := opt.Lock.apply(dst, m, g, r.bounds); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	var overlay map[image.Point]Color
+	if len(opt.Overlays) > 0 {
+		overlay = splitOverlays(dst, len(palette), opt.Overlays)
+	}
+	if opt.Canvas != nil {
+		if err := applyCanvas(dst, overlay, opt.Canvas, g, r.bounds); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return dst, m, overlay, nil
+}
+
+// place covers dst with bricks, mapping its colors through m. Studs of colors
+// missing from m are left empty.
+func place(ctx context.Context, dst *image.Paletted, m map[color.Color]Color, opt *Options, cache *buildCache, progress func(done, total int)) (*Panel, error) {
+	if opt.Placer != nil {
+		return placeWith(ctx, dst, m, opt, progress)
+	}
+	ret := newPanel(dst.Bounds())
+	helper := newHelper(opt.Bricks, dst, ret, opt)
+	if cache != nil {
+		for c, ordered := range cache.ordered {
+			helper.ordered[c] = ordered
+		}
+	}
+	size := dst.Bounds().Size()
 	for y := dst.Bounds().Min.Y; y < dst.Bounds().Max.Y; y++ {
 		for x := dst.Bounds().Min.X; x < dst.Bounds().Max.X; x++ {
-			helper.placeBrick(image.Point{x, y}, m[dst.At(x, y)])
+			c, ok := m[dst.At(x, y)]
+			if !ok {
+				continue
+			}
+			if err := helper.placeBrick(image.Point{x, y}, c); err != nil {
+				return nil, err
+			}
+		}
+		if progress != nil {
+			progress((y-dst.Bounds().Min.Y+1)*size.X, size.X*size.Y)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 	}
-	return ret
+	return ret, nil
+}
+
+// NewPanelFromPaletted builds a panel from an image already designed at one
+// pixel per stud, such as pixel art, without resizing or quantizing it.
+// mapping gives the brick color of every palette color used in img.
+func NewPanelFromPaletted(img *image.Paletted, mapping map[color.Color]Color, bricks []*Brick) (*Panel, error) {
+	if err := validateBricks(bricks); err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, errors.New("lego: empty image")
+	}
+	if err := checkSize(img.Bounds().Size(), &Options{}); err != nil {
+		return nil, err
+	}
+	byKey := make(map[[4]uint32]Color)
+	for c, brickColor := range mapping {
+		byKey[maskKey(c)] = brickColor
+	}
+	used := make([]bool, len(img.Palette))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			used[img.ColorIndexAt(x, y)] = true
+		}
+	}
+	// Reuse the pixels of img with the brick colors as palette.
+	palette := make(color.Palette, len(img.Palette))
+	m := make(map[color.Color]Color)
+	for i, c := range img.Palette {
+		brickColor, ok := byKey[maskKey(c)]
+		if !ok {
+			if used[i] {
+				return nil, fmt.Errorf("lego: no brick color for palette color %v", c)
+			}
+			palette[i] = color.Transparent
+			continue
+		}
+		palette[i] = brickColor.color
+		m[brickColor.color] = brickColor
+	}
+	// Panels start at the origin, as the drawing code expects, even for
+	// sub-images.
+	dst := &image.Paletted{Pix: img.Pix, Stride: img.Stride, Rect: image.Rectangle{image.ZP, img.Rect.Size()}, Palette: palette}
+	return place(context.Background(), dst, m, &Options{Bricks: bricks}, nil, nil)
 }
 
 func (p *Panel) Draw(scale int, outline bool) image.Image {
-	out := image.NewNRGBA(image.Rectangle{image.ZP, p.bounds.Size().Mul(scale)})
-	draw.Draw(out, out.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
-	for pos, brick := range p.bricks {
-		min := pos.Mul(scale)
-		max := min.Add(brick.Size.Mul(scale))
-		if outline {
-			draw.Draw(out, image.Rectangle{min, max}, &image.Uniform{color.NRGBA{0, 0, 0, 255}},
-				image.ZP, draw.Src)
-			min = min.Add(image.Point{1, 1})
-			max = max.Sub(image.Point{1, 1})
-			draw.Draw(out, image.Rectangle{min, max}, &image.Uniform{color.NRGBA{255, 255, 255, 255}},
-				image.ZP, draw.Src)
-			min = min.Add(image.Point{1, 1})
-			max = max.Sub(image.Point{1, 1})
-		}
-		draw.Draw(out, image.Rectangle{min, max}, &image.Uniform{brick.Color.color},
-			image.ZP, draw.Src)
-	}
-	return out
+	return p.DrawWith(&DrawOptions{Scale: scale, Outline: outline})
+}
+
+// drawBrick draws a brick at pos, with seam s, and returns the area it
+// covers.
+func drawBrick(out draw.Image, pos image.Point, brick *Brick, scale int, s seam) image.Rectangle {
+	pos = pos.Sub(brick.anchor())
+	min := pos.Mul(scale)
+	max := min.Add(brick.Size.Mul(scale))
+	r := image.Rectangle{min, max}
+	if brick.Mask != 0 {
+		drawShaped(out, pos, brick, scale, s)
+		return r
+	}
+	if s.gap > 0 {
+		draw.Draw(out, r, &image.Uniform{s.gapColor}, image.ZP, draw.Src)
+		draw.Draw(out, r.Inset(s.gap), &image.Uniform{s.lineColor}, image.ZP, draw.Src)
+	}
+	draw.Draw(out, r.Inset(s.gap+s.line), &image.Uniform{brick.Color.color}, image.ZP, draw.Src)
+	return r
+}
+
+// drawShaped draws a brick with a Mask stud by stud, with the seam only
+// along the edges of the brick.
+func drawShaped(out draw.Image, pos image.Point, brick *Brick, scale int, s seam) {
+	gap := &image.Uniform{s.gapColor}
+	line := &image.Uniform{s.lineColor}
+	for y := 0; y < brick.Size.Y; y++ {
+		for x := 0; x < brick.Size.X; x++ {
+			pt := image.Point{x, y}
+			if !brick.Covers(pt) {
+				continue
+			}
+			cell := image.Rectangle{pos.Add(pt).Mul(scale), pos.Add(pt).Add(image.Point{1, 1}).Mul(scale)}
+			if s.gap > 0 {
+				// Inset the sides without a neighbor in the same brick.
+				inset := func(r image.Rectangle, d int) image.Rectangle {
+					if !brick.Covers(pt.Add(image.Point{-1, 0})) {
+						r.Min.X += d
+					}
+					if !brick.Covers(pt.Add(image.Point{1, 0})) {
+						r.Max.X -= d
+					}
+					if !brick.Covers(pt.Add(image.Point{0, -1})) {
+						r.Min.Y += d
+					}
+					if !brick.Covers(pt.Add(image.Point{0, 1})) {
+						r.Max.Y -= d
+					}
+					return r
+				}
+				draw.Draw(out, cell, gap, image.ZP, draw.Src)
+				draw.Draw(out, inset(cell, s.gap), line, image.ZP, draw.Src)
+				cell = inset(cell, s.gap+s.line)
+			}
+			draw.Draw(out, cell, &image.Uniform{brick.Color.color}, image.ZP, draw.Src)
+			if s.gap == 0 {
+				continue
+			}
+			// Close the seam at inner corners.
+			for _, d := range []image.Point{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+				if brick.Covers(pt.Add(d)) || !brick.Covers(pt.Add(image.Point{d.X, 0})) ||
+					!brick.Covers(pt.Add(image.Point{0, d.Y})) {
+					continue
+				}
+				// square returns the n by n pixels of the cell at
+				// that corner.
+				square := func(n int) image.Rectangle {
+					min := pos.Add(pt).Mul(scale)
+					if d.X > 0 {
+						min.X += scale - n
+					}
+					if d.Y > 0 {
+						min.Y += scale - n
+					}
+					return image.Rectangle{min, min.Add(image.Point{n, n})}
+				}
+				draw.Draw(out, square(s.gap+s.line), line, image.ZP, draw.Src)
+				draw.Draw(out, square(s.gap), gap, image.ZP, draw.Src)
+			}
+		}
+	}
+}
+
+// positions returns the position of every brick, in row-major order.
+func (p *Panel) positions() []image.Point {
+	result := make([]image.Point, 0, p.bricks.len())
+	p.bricks.each(func(pos image.Point, _ *Brick) {
+		result = append(result, pos)
+	})
+	return result
+}
+
+// cells maps every covered stud to the position of the brick covering it.
+func (p *Panel) cells() *cellGrid {
+	result := &cellGrid{studSet: newStudSet(p.bounds), pos: make([]image.Point, p.bounds.Dx()*p.bounds.Dy())}
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		for y := 0; y < brick.Size.Y; y++ {
+			for x := 0; x < brick.Size.X; x++ {
+				if brick.Covers(image.Point{x, y}) {
+					result.set(pos.Add(image.Point{x, y}.Sub(brick.anchor())), pos)
+				}
+			}
+		}
+	})
+	return result
 }
 
 func (p *Panel) Size() image.Point {
@@ -221,8 +811,16 @@ func (p *Panel) Size() image.Point {
 
 func (p *Panel) CountBricks() map[Brick]int {
 	result := make(map[Brick]int)
-	for _, brick := range p.bricks {
+	p.bricks.each(func(_ image.Point, brick *Brick) {
 		result[brick.canonical()] += 1
-	}
+	})
 	return result
 }
+
+// ForEach calls fn for every brick in the panel, with the position of its
+// top-left stud, in row-major order.
+func (p *Panel) ForEach(fn func(pos image.Point, b Brick)) {
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		fn(pos, *brick)
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// gradient returns a test image with smooth color changes in both
+// directions.
+func gradient(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(255 * x / w), uint8(255 * y / h), uint8(255 - 255*x/w), 255})
+		}
+	}
+	return img
+}
+
+// studColors returns the color of every stud covered by a brick of p.
+func studColors(p *Panel) map[image.Point]Color {
+	colors := make(map[image.Point]Color)
+	p.ForEach(func(pos image.Point, b Brick) {
+		min := pos.Sub(b.anchor())
+		for y := 0; y < b.Size.Y; y++ {
+			for x := 0; x < b.Size.X; x++ {
+				if b.Covers(image.Point{x, y}) {
+					colors[min.Add(image.Point{x, y})] = b.Color
+				}
+			}
+		}
+	})
+	return colors
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file; run go test -update if the change is intended", name)
+	}
+}
+
+// goldenPanel builds a small panel, frame corners included, for the golden
+// files.
+func goldenPanel(t *testing.T) *Panel {
+	t.Helper()
+	p, err := NewPanel(gradient(64, 48), &Options{
+		Width:  16,
+		Bricks: ALL_BRICKS,
+		Frame:  FrameSpec{Width: 1, Color: BLACK, Corners: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestGoldenOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(p *Panel, buf *bytes.Buffer) error
+	}{
+		{"foreach.golden", func(p *Panel, buf *bytes.Buffer) error {
+			p.ForEach(func(pos image.Point, b Brick) {
+				fmt.Fprintf(buf, "%v %v\n", pos, b)
+			})
+			return nil
+		}},
+		{"bom.csv.golden", func(p *Panel, buf *bytes.Buffer) error {
+			return p.WriteBOM(buf, CSV)
+		}},
+		{"panel.svg.golden", func(p *Panel, buf *bytes.Buffer) error {
+			return p.ExportSVG(buf, &SVGOptions{Outline: true, Studs: true, Labels: true})
+		}},
+		{"panel.lxfml.golden", func(p *Panel, buf *bytes.Buffer) error {
+			return p.ExportLXFML(buf, "golden")
+		}},
+	} {
+		// Build twice, to catch output that depends on map order.
+		var outputs [2]bytes.Buffer
+		for i := range outputs {
+			if err := tc.write(goldenPanel(t), &outputs[i]); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		if !bytes.Equal(outputs[0].Bytes(), outputs[1].Bytes()) {
+			t.Errorf("%s: output changes between runs", tc.name)
+		}
+		checkGolden(t, tc.name, outputs[0].Bytes())
+	}
+}
+
+func TestNewPanelFromPalettedSubImage(t *testing.T) {
+	palette := color.Palette{WHITE.color, BRIGHT_RED.color}
+	img := image.NewPaletted(image.Rect(0, 0, 30, 30), palette)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 30; x++ {
+			img.SetColorIndex(x, y, uint8((x/5+y/5)%2))
+		}
+	}
+	sub := img.SubImage(image.Rect(10, 10, 20, 20)).(*image.Paletted)
+	mapping := map[color.Color]Color{WHITE.color: WHITE, BRIGHT_RED.color: BRIGHT_RED}
+	p, err := NewPanelFromPaletted(sub, mapping, BASIC_BRICKS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.bounds.Min != image.ZP {
+		t.Errorf("bounds %v, want a zero origin", p.bounds)
+	}
+	if got := p.Size(); got != image.Pt(10, 10) {
+		t.Errorf("size %v, want 10x10", got)
+	}
+	out := p.Draw(4, false)
+	drawn := 0
+	b := out.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := out.At(x, y).RGBA(); a != 0 {
+				drawn++
+			}
+		}
+	}
+	if drawn != b.Dx()*b.Dy() {
+		t.Errorf("%d of %d pixels drawn", drawn, b.Dx()*b.Dy())
+	}
+	// The stud at (x, y) of the panel is the pixel at (x+10, y+10) of img.
+	cells := p.cells()
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			want := mapping[palette[img.ColorIndexAt(x+10, y+10)]]
+			if c, ok := colorAt(p, cells, image.Point{x, y}); !ok || c != want {
+				t.Fatalf("stud (%d, %d) is %s, want %s", x, y, c.name, want.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+
+// Package legohttp serves Lego panels over HTTP.
+//
+// POST /panel accepts a multipart form with the image in the "image" field
+// and the options "width", "height", "fit", "palette", "dither", "scale" and
+// "outline", and responds with a JSON object holding the rendered panel as a
+// base64 PNG, the list of parts and the estimated cost.
+package legohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mrbubble/lego"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+var fits = map[string]lego.Fit{
+	"stretch": lego.Stretch,
+	"contain": lego.Contain,
+	"cover":   lego.Cover,
+	"pad":     lego.Pad,
+}
+
+// Server generates panels from uploaded images. The zero value is ready to
+// use.
+type Server struct {
+	// Prices used for the cost estimate; defaults to lego.ESTIMATED_PRICES.
+	Prices lego.PriceList
+	// MaxWidth and MaxHeight limit the panel size, in studs; default to 256.
+	MaxWidth  uint
+	MaxHeight uint
+	// MaxUpload limits the size of the request body; defaults to 16MB.
+	MaxUpload int64
+	// MaxPixels limits the size of uploaded images once decoded; defaults
+	// to 40 megapixels.
+	MaxPixels int
+}
+
+// Part is a line of the parts list.
+type Part struct {
+	Size  string  `json:"size"`
+	Color string  `json:"color"`
+	Count int     `json:"count"`
+	Price float64 `json:"price"`
+}
+
+// Response is the result of POST /panel.
+type Response struct {
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	PNG    []byte  `json:"png"`
+	Parts  []Part  `json:"parts"`
+	Cost   float64 `json:"cost"`
+}
+
+// Handler returns the handler serving the server's endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panel", s.servePanel)
+	return mux
+}
+
+func (s *Server) limits() (uint, uint, int64) {
+	w, h, upload := s.MaxWidth, s.MaxHeight, s.MaxUpload
+	if w == 0 {
+		w = 256
+	}
+	if h == 0 {
+		h = 256
+	}
+	if upload == 0 {
+		upload = 16 << 20
+	}
+	return w, h, upload
+}
+
+func (s *Server) maxPixels() int {
+	if s.MaxPixels == 0 {
+		return 40000000
+	}
+	return s.MaxPixels
+}
+
+func formUint(r *http.Request, key string, def uint) (uint, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return uint(n), nil
+}
+
+func formBool(r *http.Request, key string, def bool) (bool, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return b, nil
+}
+
+func (s *Server) options(r *http.Request) (*lego.Options, int, bool, error) {
+	maxWidth, maxHeight, _ := s.limits()
+	opt := &lego.Options{}
+	var err error
+	if opt.Width, err = formUint(r, "width", 48); err != nil {
+		return nil, 0, false, err
+	}
+	if opt.Height, err = formUint(r, "height", 0); err != nil {
+		return nil, 0, false, err
+	}
+	if opt.Width > maxWidth || opt.Height > maxHeight {
+		return nil, 0, false, fmt.Errorf("panel larger than %dx%d", maxWidth, maxHeight)
+	}
+	if opt.Width == 0 && opt.Height == 0 {
+		return nil, 0, false, fmt.Errorf("width or height must be set")
+	}
+	var ok bool
+	if opt.Fit, ok = fits[r.FormValue("fit")]; !ok && r.FormValue("fit") != "" {
+		return nil, 0, false, fmt.Errorf("unknown fit %q", r.FormValue("fit"))
+	}
+	palette := r.FormValue("palette")
+	if palette == "" {
+		palette = "basic"
+	}
+	if opt.Bricks, ok = lego.Palettes()[palette]; !ok {
+		return nil, 0, false, fmt.Errorf("unknown palette %q", palette)
+	}
+	if opt.Dither, err = formBool(r, "dither", false); err != nil {
+		return nil, 0, false, err
+	}
+	scale, err := formUint(r, "scale", 16)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if scale == 0 || scale > 64 {
+		return nil, 0, false, fmt.Errorf("scale must be between 1 and 64")
+	}
+	outline, err := formBool(r, "outline", true)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err := opt.Validate(); err != nil {
+		return nil, 0, false, err
+	}
+	return opt, int(scale), outline, nil
+}
+
+func parts(p *lego.Panel, prices lego.PriceList) []Part {
+	var result []Part
+	for brick, n := range p.CountBricks() {
+		c := brick.Color
+		result = append(result, Part{
+			Size:  fmt.Sprintf("%dx%d", brick.Size.X, brick.Size.Y),
+			Color: c.Name(),
+			Count: n,
+			Price: prices[brick],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Color != result[j].Color {
+			return result[i].Color < result[j].Color
+		}
+		return result[i].Size < result[j].Size
+	})
+	return result
+}
+
+func (s *Server) servePanel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	_, _, maxUpload := s.limits()
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+	if err := r.ParseMultipartForm(maxUpload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opt, scale, outline, err := s.options(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "missing image: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	// Check the size before decoding, which allocates the whole image.
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if maxPixels := s.maxPixels(); config.Width <= 0 || config.Height <= 0 ||
+		config.Width > maxPixels/config.Height {
+		http.Error(w, fmt.Sprintf("image larger than %d pixels", maxPixels), http.StatusBadRequest)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	panel, err := lego.NewPanelContext(r.Context(), img, opt, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, panel.Draw(scale, outline)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	prices := s.Prices
+	if prices == nil {
+		prices = lego.ESTIMATED_PRICES
+	}
+	size := panel.Size()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&Response{
+		Width:  size.X,
+		Height: size.Y,
+		PNG:    buf.Bytes(),
+		Parts:  parts(panel, prices),
+		Cost:   panel.Cost(prices),
+	})
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package legohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+// testPNG returns a w by h PNG image with a few colors.
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(255 * x / w), uint8(255 * y / h), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// post sends a multipart form with the fields and, unless it is nil, the
+// image to s.
+func post(t *testing.T, s *Server, fields map[string]string, img []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	if img != nil {
+		fw, err := mw.CreateFormFile("image", "image.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(img)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/panel", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServePanel(t *testing.T) {
+	rec := post(t, &Server{}, map[string]string{"width": "16", "scale": "4", "palette": "all"}, testPNG(t, 64, 32))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type is %q", ct)
+	}
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Width != 16 || resp.Height != 8 {
+		t.Errorf("panel is %dx%d, want 16x8", resp.Width, resp.Height)
+	}
+	img, err := png.Decode(bytes.NewReader(resp.PNG))
+	if err != nil {
+		t.Fatalf("response PNG: %v", err)
+	}
+	if got, want := img.Bounds().Size(), image.Pt(64, 32); got != want {
+		t.Errorf("PNG is %v, want %v", got, want)
+	}
+
+	// The parts cover the panel, sorted by color and size, and add up to
+	// the cost.
+	studs, cost := 0, 0.0
+	for _, part := range resp.Parts {
+		var w, h int
+		if _, err := fmt.Sscanf(part.Size, "%dx%d", &w, &h); err != nil {
+			t.Errorf("part size %q: %v", part.Size, err)
+		}
+		if part.Color == "" || part.Count <= 0 || part.Price <= 0 {
+			t.Errorf("part %+v", part)
+		}
+		studs += w * h * part.Count
+		cost += part.Price * float64(part.Count)
+	}
+	if studs != 16*8 {
+		t.Errorf("parts cover %d studs, want %d", studs, 16*8)
+	}
+	if !sort.SliceIsSorted(resp.Parts, func(i, j int) bool {
+		a, b := resp.Parts[i], resp.Parts[j]
+		return a.Color < b.Color || a.Color == b.Color && a.Size < b.Size
+	}) {
+		t.Errorf("parts are not sorted: %+v", resp.Parts)
+	}
+	if math.Abs(cost-resp.Cost) > 1e-9 {
+		t.Errorf("cost is %v, parts add up to %v", resp.Cost, cost)
+	}
+}
+
+func TestServePanelErrors(t *testing.T) {
+	img := testPNG(t, 64, 32)
+	for _, tc := range []struct {
+		name   string
+		s      *Server
+		fields map[string]string
+		img    []byte
+		want   int
+	}{
+		{"bad width", &Server{}, map[string]string{"width": "wide"}, img, http.StatusBadRequest},
+		{"too wide", &Server{MaxWidth: 32}, map[string]string{"width": "64"}, img, http.StatusBadRequest},
+		{"no size", &Server{}, map[string]string{"width": "0"}, img, http.StatusBadRequest},
+		{"unknown fit", &Server{}, map[string]string{"fit": "squash"}, img, http.StatusBadRequest},
+		{"unknown palette", &Server{}, map[string]string{"palette": "neon"}, img, http.StatusBadRequest},
+		{"bad scale", &Server{}, map[string]string{"scale": "100"}, img, http.StatusBadRequest},
+		{"bad dither", &Server{}, map[string]string{"dither": "maybe"}, img, http.StatusBadRequest},
+		{"missing image", &Server{}, nil, nil, http.StatusBadRequest},
+		{"not an image", &Server{}, nil, []byte("hello"), http.StatusBadRequest},
+		{"body too large", &Server{MaxUpload: 100}, nil, img, http.StatusBadRequest},
+		{"too many pixels", &Server{MaxPixels: 1000}, nil, img, http.StatusBadRequest},
+	} {
+		rec := post(t, tc.s, tc.fields, tc.img)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/panel", nil)
+	rec := httptest.NewRecorder()
+	(&Server{}).Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "POST" {
+		t.Errorf("GET: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// PaletteLock forces the color of whole regions of the panel regardless of
+// quantization. Mask is an image covering the same area as the source image;
+// every pixel whose mask value is a key of Colors gets the associated Color.
+// Mask values not listed in Colors are quantized as usual.
+type PaletteLock struct {
+	Mask   image.Image
+	Colors map[color.Color]Color
+}
+
+// maskKey normalizes colors so that, e.g., color.White and color.Gray{255}
+// select the same lock.
+func maskKey(c color.Color) [4]uint32 {
+	r, g, b, a := c.RGBA()
+	return [4]uint32{r, g, b, a}
+}
+
+// scaleNearest resamples img to size without blending colors, so mask values
+// survive resizing exactly.
+func scaleNearest(img image.Image, size image.Point) *image.RGBA64 {
+	b := img.Bounds()
+	out := image.NewRGBA64(image.Rectangle{image.ZP, size})
+	for y := 0; y < size.Y; y++ {
+		sy := b.Min.Y + (2*y+1)*b.Dy()/(2*size.Y)
+		for x := 0; x < size.X; x++ {
+			sx := b.Min.X + (2*x+1)*b.Dx()/(2*size.X)
+			out.Set(x, y, img.At(sx, sy))
+		}
+	}
+	return out
+}
+
+func (l *PaletteLock) apply(dst *image.Paletted, m map[color.Color]Color, g geometry, src image.Rectangle) error {
+	forced := make(map[[4]uint32]uint8)
+	for value, c := range l.Colors {
+		if _, ok := m[c.color]; !ok {
+			return fmt.Errorf("lego: locked color %s has no bricks", c.name)
+		}
+		forced[maskKey(value)] = uint8(dst.Palette.Index(c.color))
+	}
+	mask := scaleNearest(crop(l.Mask, g.project(src, l.Mask.Bounds())), g.size)
+	min := dst.Bounds().Min.Add(g.offset)
+	for y := 0; y < g.size.Y; y++ {
+		for x := 0; x < g.size.X; x++ {
+			if i, ok := forced[maskKey(mask.At(x, y))]; ok {
+				dst.SetColorIndex(min.X+x, min.Y+y, i)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+)
+
+// colorLUT maps opaque colors to the index of the nearest palette color
+// through a table with 1<<bits cells per channel. A cell holds -1 where its
+// colors don't all have the same nearest palette color, and those are
+// looked up in full, so it agrees with Palette.Index but for colors almost
+// exactly between two palette colors, which its rounding may send either
+// way.
+type colorLUT struct {
+	bits  uint
+	cells []int16
+}
+
+// newColorLUT builds the table for palette. As the colors nearest to a
+// palette color form a convex region, a cell is entirely in one when its
+// eight corners are.
+func newColorLUT(palette color.Palette, bits int) *colorLUT {
+	l := &colorLUT{bits: uint(bits), cells: make([]int16, 1<<(3*uint(bits)))}
+	n := 1 << l.bits
+	shift := 16 - l.bits
+	// ends holds the low and high ends of every cell along a channel.
+	ends := make([]uint16, 2*n)
+	for i := 0; i < n; i++ {
+		ends[2*i] = uint16(i << shift)
+		ends[2*i+1] = uint16((i+1)<<shift - 1)
+	}
+	index := func(r, g, b int) int {
+		return palette.Index(color.RGBA64{ends[r], ends[g], ends[b], 0xffff})
+	}
+	for r := 0; r < n; r++ {
+		for g := 0; g < n; g++ {
+			for b := 0; b < n; b++ {
+				first := index(2*r, 2*g, 2*b)
+				cell := int16(first)
+				for k := 1; k < 8 && cell >= 0; k++ {
+					if index(2*r+(k>>2&1), 2*g+(k>>1&1), 2*b+(k&1)) != first {
+						cell = -1
+					}
+				}
+				l.cells[(r<<l.bits|g)<<l.bits|b] = cell
+			}
+		}
+	}
+	return l
+}
+
+// lookup returns the palette index of the opaque color with 16-bit
+// channels r, g and b, if its cell has one.
+func (l *colorLUT) lookup(r, g, b uint32) (uint8, bool) {
+	shift := 16 - l.bits
+	cell := l.cells[(r>>shift<<l.bits|g>>shift)<<l.bits|b>>shift]
+	return uint8(cell), cell >= 0
+}
+
+// opaquePixels returns a function reading the 16-bit channels of the pixels
+// of img, and whether they are opaque, without going through img.At for the
+// image types the resizer produces.
+func opaquePixels(img image.Image) func(x, y int) (r, g, b uint32, ok bool) {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, bool) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return uint32(p[0]) * 0x101, uint32(p[1]) * 0x101, uint32(p[2]) * 0x101, p[3] == 0xff
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32, bool) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return uint32(p[0]) * 0x101, uint32(p[1]) * 0x101, uint32(p[2]) * 0x101, p[3] == 0xff
+		}
+	case *image.RGBA64:
+		return func(x, y int) (uint32, uint32, uint32, bool) {
+			c := img.RGBA64At(x, y)
+			return uint32(c.R), uint32(c.G), uint32(c.B), c.A == 0xffff
+		}
+	}
+	return func(x, y int) (uint32, uint32, uint32, bool) {
+		r, g, b, a := img.At(x, y).RGBA()
+		return r, g, b, a == 0xffff
+	}
+}
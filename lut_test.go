@@ -0,0 +1,58 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestColorLUT(t *testing.T) {
+	palette, _ := paletteOf(ALL_BRICKS)
+	rng := rand.New(rand.NewSource(1))
+	for _, bits := range []int{1, 3, 5, 7} {
+		l := newColorLUT(palette, bits)
+		found, bad := 0, 0
+		for i := 0; i < 20000; i++ {
+			r, g, b := uint32(rng.Intn(1<<16)), uint32(rng.Intn(1<<16)), uint32(rng.Intn(1<<16))
+			j, ok := l.lookup(r, g, b)
+			if !ok {
+				continue
+			}
+			found++
+			if int(j) != palette.Index(color.RGBA64{uint16(r), uint16(g), uint16(b), 0xffff}) {
+				bad++
+			}
+		}
+		if bad > found/1000 {
+			t.Errorf("bits %d: %d of %d colors mapped to another palette color", bits, bad, found)
+		}
+	}
+}
+
+func TestLUTPanels(t *testing.T) {
+	img := gradient(64, 48)
+	want, err := NewPanel(img, &Options{Width: 64, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCells := want.cells()
+	for _, bits := range []int{3, 5, 7} {
+		got, err := NewPanel(img, &Options{Width: 64, Bricks: ALL_BRICKS, LUTBits: bits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotCells := got.cells()
+		differ := 0
+		wantCells.each(func(pt, pos image.Point) {
+			if other, _ := gotCells.at(pt); want.bricks.at(pos).Color != got.bricks.at(other).Color {
+				differ++
+			}
+		})
+		// Only colors almost exactly between two palette colors may differ.
+		if differ > wantCells.len()/100 {
+			t.Errorf("bits %d: %d of %d studs differ in color", bits, differ, wantCells.len())
+		}
+	}
+}
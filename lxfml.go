@@ -0,0 +1,151 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"math"
+)
+
+// ldd converts a distance in studs to LDD units, 0.8 per stud.
+func ldd(studs float64) float64 {
+	return studs * 8 / 10
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Quarter turns around the vertical axis, as in ExportLXFML, and where each
+// one takes the stud at (x, z) of a part.
+var turns = []struct {
+	rotation string
+	apply    func(image.Point) image.Point
+}{
+	{"1,0,0,0,1,0,0,0,1", func(p image.Point) image.Point { return p }},
+	{"0,0,-1,0,1,0,1,0,0", func(p image.Point) image.Point { return image.Point{p.Y, -p.X} }},
+	{"-1,0,0,0,1,0,0,0,-1", func(p image.Point) image.Point { return image.Point{-p.X, -p.Y} }},
+	{"0,0,1,0,1,0,-1,0,0", func(p image.Point) image.Point { return image.Point{-p.Y, p.X} }},
+}
+
+// shapedRotation returns the rotation that turns the canonical part of a
+// brick with a Mask into the brick, and the position of the part's origin
+// relative to the brick's top-left stud.
+func shapedRotation(b Brick) (string, image.Point) {
+	c := b.canonical()
+	for _, turn := range turns {
+		var cells []image.Point
+		min := image.Point{math.MaxInt32, math.MaxInt32}
+		for y := 0; y < c.Size.Y; y++ {
+			for x := 0; x < c.Size.X; x++ {
+				if c.Covers(image.Point{x, y}) {
+					pt := turn.apply(image.Point{x, y})
+					cells = append(cells, pt)
+					min.X, min.Y = minInt(min.X, pt.X), minInt(min.Y, pt.Y)
+				}
+			}
+		}
+		matches := true
+		for _, pt := range cells {
+			if !b.Covers(pt.Sub(min)) {
+				matches = false
+			}
+		}
+		if matches {
+			return turn.rotation, image.ZP.Sub(min)
+		}
+	}
+	return turns[0].rotation, image.ZP
+}
+
+// ExportLXFML writes the panel as a LEGO Digital Designer model, lying
+// studs up. Bricks use their LEGO design numbers and LEGO color IDs as
+// materials, so colors and parts survive the import exactly.
+func (p *Panel) ExportLXFML(w io.Writer, name string) error {
+	out := &errWriter{w: w}
+	out.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\" ?>\n")
+	out.printf("<LXFML versionMajor=\"5\" versionMinor=\"0\" name=\"%s\">\n", xmlEscape(name))
+	out.printf("  <Meta>\n")
+	out.printf("    <Application name=\"LEGO Digital Designer\" versionMajor=\"4\" versionMinor=\"3\"/>\n")
+	out.printf("    <Brand name=\"LDD\"/>\n")
+	out.printf("    <BrickSet version=\"1264\"/>\n")
+	out.printf("  </Meta>\n")
+	out.printf("  <Cameras>\n")
+	size := p.bounds.Size()
+	center := [2]float64{ldd(float64(size.X) / 2), ldd(float64(size.Y) / 2)}
+	distance := ldd(float64(size.X + size.Y))
+	out.printf("    <Camera refID=\"0\" fieldOfView=\"80\" distance=\"%g\" transformation=\"1,0,0,0,0,-1,0,1,0,%g,%g,%g\"/>\n",
+		distance, center[0], distance, center[1])
+	out.printf("  </Cameras>\n")
+	out.printf("  <Bricks cameraRef=\"0\">\n")
+	positions := p.positions()
+	for i, pos := range positions {
+		brick := p.bricks.at(pos)
+		design := brick.PartNumber()
+		if p.round {
+			design = artPart
+		}
+		if design == "" {
+			return fmt.Errorf("lego: no design number for %v", brick)
+		}
+		rel := pos.Sub(p.bounds.Min).Sub(brick.anchor())
+		// Parts lie along the X axis with their origin at the first stud.
+		// Bricks standing along the rows are turned a quarter around the
+		// vertical axis, which makes them grow towards the top.
+		rotation := "1,0,0,0,1,0,0,0,1"
+		x, z := float64(rel.X), float64(rel.Y)
+		if brick.Mask != 0 {
+			var offset image.Point
+			rotation, offset = shapedRotation(*brick)
+			x, z = x+float64(offset.X), z+float64(offset.Y)
+		} else if brick.Size.X < brick.Size.Y {
+			rotation = "0,0,-1,0,1,0,1,0,0"
+			z += float64(brick.Size.Y - 1)
+		}
+		out.printf("    <Brick refID=\"%d\" designID=\"%s\">\n", i, design)
+		out.printf("      <Part refID=\"%d\" designID=\"%s\" materials=\"%d\">\n", i, design, brick.Color.id)
+		out.printf("        <Bone refID=\"%d\" transformation=\"%s,%g,0,%g\">\n", i, rotation, ldd(x), ldd(z))
+		out.printf("        </Bone>\n")
+		out.printf("      </Part>\n")
+		out.printf("    </Brick>\n")
+	}
+	// Overlay plates sit on top of the bricks, 0.96 units high.
+	for i, pt := range p.overlayPositions() {
+		id := len(positions) + i
+		c := p.overlay[pt]
+		rel := pt.Sub(p.bounds.Min)
+		out.printf("    <Brick refID=\"%d\" designID=\"%s\">\n", id, overlayPart)
+		out.printf("      <Part refID=\"%d\" designID=\"%s\" materials=\"%d\">\n", id, overlayPart, c.id)
+		out.printf("        <Bone refID=\"%d\" transformation=\"1,0,0,0,1,0,0,0,1,%g,0.96,%g\">\n",
+			id, ldd(float64(rel.X)), ldd(float64(rel.Y)))
+		out.printf("        </Bone>\n")
+		out.printf("      </Part>\n")
+		out.printf("    </Brick>\n")
+	}
+	out.printf("  </Bricks>\n")
+	out.printf("  <RigidSystems>\n  </RigidSystems>\n")
+	out.printf("  <GroupSystems>\n    <BrickGroupSystem>\n    </BrickGroupSystem>\n  </GroupSystems>\n")
+	out.printf("  <BuildingInstructions>\n  </BuildingInstructions>\n")
+	out.printf("</LXFML>\n")
+	return out.err
+}
+
+// ExportLXF writes the panel as an LXF file, the zipped LXFML that LEGO
+// Digital Designer saves and BrickLink Stud.io imports.
+func (p *Panel) ExportLXF(w io.Writer, name string) error {
+	z := zip.NewWriter(w)
+	f, err := z.Create("IMAGE100.LXFML")
+	if err != nil {
+		return err
+	}
+	if err := p.ExportLXFML(f, name); err != nil {
+		return err
+	}
+	return z.Close()
+}
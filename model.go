@@ -0,0 +1,114 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// VoxelGrid is a simple 3D model as a stack of layers, one brick high, from
+// the bottom up. Each layer is an image with one pixel per stud: mostly
+// transparent pixels are empty, and the others get the closest brick color.
+// All layers must have the same bounds.
+type VoxelGrid []image.Image
+
+// Model is a sculpture built from layers of bricks, each layer crossing the
+// one below so they hold together.
+type Model struct {
+	layers []*Panel
+	bounds image.Rectangle
+}
+
+// NewModel builds voxels with the bricks in Options.Bricks, layer by layer.
+// Only the options affecting placement are used: Bricks, MaxInventory,
+// which is shared by all layers, BrickWeights and Seed.
+func NewModel(voxels VoxelGrid, opt *Options) (*Model, error) {
+	if err := validateBricks(opt.Bricks); err != nil {
+		return nil, err
+	}
+	if len(voxels) == 0 {
+		return nil, errors.New("lego: no layers")
+	}
+	var palette color.Palette
+	m := make(map[color.Color]Color)
+	for _, brick := range opt.Bricks {
+		if _, ok := m[brick.Color.color]; !ok {
+			m[brick.Color.color] = brick.Color
+			palette = append(palette, brick.Color.color)
+		}
+	}
+	model := &Model{bounds: voxels[0].Bounds()}
+	used := make(Inventory)
+	for level, img := range voxels {
+		if img.Bounds() != model.bounds {
+			return nil, fmt.Errorf("lego: layer %d bounds %v differ from %v", level+1, img.Bounds(), model.bounds)
+		}
+		colors := make(map[image.Point]Color)
+		for y := model.bounds.Min.Y; y < model.bounds.Max.Y; y++ {
+			for x := model.bounds.Min.X; x < model.bounds.Max.X; x++ {
+				c := img.At(x, y)
+				if _, _, _, a := c.RGBA(); a >= 0x8000 {
+					colors[image.Point{x, y}] = m[palette.Convert(c)]
+				}
+			}
+		}
+		layer, err := placeLayer(model.bounds, colors, opt, level%2 == 1, used)
+		if err != nil {
+			return nil, fmt.Errorf("lego: layer %d: %s", level+1, strings.TrimPrefix(err.Error(), "lego: "))
+		}
+		for brick, n := range layer.CountBricks() {
+			used[brick] += n
+		}
+		model.layers = append(model.layers, layer)
+	}
+	return model, nil
+}
+
+// Size returns the footprint of the model, in studs.
+func (m *Model) Size() image.Point {
+	return m.bounds.Size()
+}
+
+// Layers returns the layers of the model, from the bottom up.
+func (m *Model) Layers() []*Panel {
+	return append([]*Panel(nil), m.layers...)
+}
+
+func (m *Model) CountBricks() map[Brick]int {
+	result := make(map[Brick]int)
+	for _, layer := range m.layers {
+		for brick, n := range layer.CountBricks() {
+			result[brick] += n
+		}
+	}
+	return result
+}
+
+// ExportLDraw writes the model as an LDraw model, one building step per
+// layer.
+func (m *Model) ExportLDraw(w io.Writer, name string) error {
+	out := &errWriter{w: w}
+	ldrawHeader(out, name)
+	for level, layer := range m.layers {
+		for _, pos := range layer.positions() {
+			brick := *layer.bricks.at(pos)
+			part := brick.PartNumber()
+			if part == "" {
+				return fmt.Errorf("lego: no design number for %v", brick)
+			}
+			ldrawPart(out, part, brick, pos.Sub(m.bounds.Min), -ldrawBrick*(level+1))
+		}
+		ldrawStep(out)
+	}
+	return out.err
+}
+
+// WriteInstructions writes, for every layer from the bottom up, the bricks
+// it needs and where each goes, by column and row of its top-left stud.
+func (m *Model) WriteInstructions(w io.Writer) error {
+	return writeLayers(w, m.layers, m.bounds, "brick")
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestNewModel(t *testing.T) {
+	// A stepped pyramid: a red 4x4 base, a blue 2x2 on it and a white stud
+	// on top.
+	layer := func(c Color, r image.Rectangle) image.Image {
+		img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		draw.Draw(img, r, &image.Uniform{c.color}, image.ZP, draw.Src)
+		return img
+	}
+	voxels := VoxelGrid{
+		layer(BRIGHT_RED, image.Rect(0, 0, 4, 4)),
+		layer(BRIGHT_BLUE, image.Rect(1, 1, 3, 3)),
+		layer(WHITE, image.Rect(1, 1, 2, 2)),
+	}
+	m, err := NewModel(voxels, &Options{Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Size() != (image.Point{4, 4}) {
+		t.Errorf("size %v", m.Size())
+	}
+	layers := m.Layers()
+	if len(layers) != len(voxels) {
+		t.Fatalf("%d layers", len(layers))
+	}
+	total := make(map[Brick]int)
+	for i, want := range []struct {
+		color Color
+		studs int
+	}{{BRIGHT_RED, 16}, {BRIGHT_BLUE, 4}, {WHITE, 1}} {
+		colors := studColors(layers[i])
+		if len(colors) != want.studs {
+			t.Errorf("layer %d covers %d studs, want %d", i, len(colors), want.studs)
+		}
+		for pt, c := range colors {
+			if c != want.color || voxels[i].At(pt.X, pt.Y) != want.color.color {
+				t.Errorf("layer %d has %v at %v", i, c, pt)
+			}
+		}
+		for brick, n := range layers[i].CountBricks() {
+			total[brick] += n
+		}
+	}
+	counts := m.CountBricks()
+	if len(counts) != len(total) {
+		t.Errorf("%d kinds of bricks, want %d", len(counts), len(total))
+	}
+	for brick, n := range total {
+		if counts[brick] != n {
+			t.Errorf("%d bricks %v, want %d", counts[brick], brick, n)
+		}
+	}
+	if _, err := NewModel(nil, &Options{Bricks: BASIC_BRICKS}); err == nil {
+		t.Error("built a model without layers")
+	}
+	odd := append(VoxelGrid{image.NewNRGBA(image.Rect(0, 0, 3, 3))}, voxels...)
+	if _, err := NewModel(odd, &Options{Bricks: BASIC_BRICKS}); err == nil {
+		t.Error("built a model with layers of different sizes")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// Edge selects sides of a panel, and can be combined with |.
+type Edge int
+
+const (
+	TopEdge Edge = 1 << iota
+	BottomEdge
+	LeftEdge
+	RightEdge
+)
+
+// MountSpec configures Panel.MountingPlan.
+type MountSpec struct {
+	// Length of the Technic bricks, in studs: 2, 4, 6 or 8. It defaults
+	// to 2.
+	Length int
+	// Interval is the distance, in studs, between the starts of
+	// neighboring Technic bricks along an edge. It defaults to 8.
+	Interval int
+	// Edges to mount along. It defaults to TopEdge, to hang the panel.
+	Edges Edge
+}
+
+// mounts returns where the Technic bricks of spec go along the edges of
+// bounds, as the rectangles they cover.
+func (spec MountSpec) mounts(bounds image.Rectangle) []image.Rectangle {
+	var result []image.Rectangle
+	// along places bricks every Interval studs over n studs, centered.
+	along := func(n int, at func(i int) image.Rectangle) {
+		if n < spec.Length {
+			return
+		}
+		count := (n-spec.Length)/spec.Interval + 1
+		start := (n - spec.Length - (count-1)*spec.Interval) / 2
+		for k := 0; k < count; k++ {
+			result = append(result, at(start+k*spec.Interval))
+		}
+	}
+	size := bounds.Size()
+	horizontal := func(y int) func(int) image.Rectangle {
+		return func(i int) image.Rectangle {
+			return image.Rect(i, y, i+spec.Length, y+1).Add(bounds.Min)
+		}
+	}
+	vertical := func(x int) func(int) image.Rectangle {
+		return func(i int) image.Rectangle {
+			return image.Rect(x, i, x+1, i+spec.Length).Add(bounds.Min)
+		}
+	}
+	if spec.Edges&TopEdge != 0 {
+		along(size.X, horizontal(0))
+	}
+	if spec.Edges&BottomEdge != 0 {
+		along(size.X, horizontal(size.Y-1))
+	}
+	if spec.Edges&LeftEdge != 0 {
+		along(size.Y, vertical(0))
+	}
+	if spec.Edges&RightEdge != 0 {
+		along(size.Y, vertical(size.X-1))
+	}
+	return result
+}
+
+// MountingPlan returns a copy of the panel with Technic bricks, whose holes
+// take pins or screws for wall mounting, laid at regular intervals along
+// its edges. Each takes the most common color of the studs it covers; the
+// bricks it displaces are laid again around it, so CountBricks and WriteBOM
+// list the Technic bricks in place of the ones they replace. Spots where a
+// Technic brick would cover a hole in the panel or a shaped brick, such as
+// the corner of a frame, or overlap another Technic brick, are skipped. The
+// Technic bricks are tagged MOUNT_TAG.
+func (p *Panel) MountingPlan(spec MountSpec) (*Panel, error) {
+	if p.round {
+		return nil, errors.New("lego: cannot mount a panel of round plates")
+	}
+	if spec.Length == 0 {
+		spec.Length = 2
+	}
+	if spec.Interval == 0 {
+		spec.Interval = 8
+	}
+	if spec.Edges == 0 {
+		spec.Edges = TopEdge
+	}
+	if _, ok := technicParts[image.Point{1, spec.Length}]; !ok {
+		return nil, errors.New("lego: no Technic brick of that length")
+	}
+	if spec.Interval < 0 {
+		return nil, errors.New("lego: negative mounting interval")
+	}
+
+	ret := newPanel(p.bounds)
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		b := *brick
+		ret.bricks.set(pos, &b)
+	})
+	if p.overlay != nil {
+		ret.overlay = make(map[image.Point]Color, len(p.overlay))
+		for pt, c := range p.overlay {
+			ret.overlay[pt] = c
+		}
+	}
+
+	cells := p.cells()
+	reserved := newStudSet(p.bounds)
+	colors := make(map[image.Point]Color)
+	technic := make(map[image.Point]Brick)
+	// free reports whether r only covers plain bricks that are not
+	// reserved yet.
+	free := func(r image.Rectangle) bool {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				pt := image.Point{x, y}
+				pos, ok := cells.at(pt)
+				if !ok || reserved.has(pt) {
+					return false
+				}
+				if brick := p.bricks.at(pos); brick.Mask != 0 || brick.Technic {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, r := range spec.mounts(p.bounds) {
+		if !free(r) {
+			continue
+		}
+		count := make(map[Color]int)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				pos, _ := cells.at(image.Point{x, y})
+				count[p.bricks.at(pos).Color]++
+			}
+		}
+		var best Color
+		for c, n := range count {
+			if best.color == nil || n > count[best] || n == count[best] && c.name < best.name {
+				best = c
+			}
+		}
+		// Take off the bricks under the Technic brick, to lay again.
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				pt := image.Point{x, y}
+				reserved.add(pt)
+				pos, _ := cells.at(pt)
+				brick := ret.bricks.at(pos)
+				if brick == nil {
+					continue
+				}
+				for by := 0; by < brick.Size.Y; by++ {
+					for bx := 0; bx < brick.Size.X; bx++ {
+						colors[pos.Add(image.Point{bx, by})] = brick.Color
+					}
+				}
+				ret.bricks.set(pos, nil)
+			}
+		}
+		technic[r.Min] = Brick{r.Size(), best, 0, true}
+	}
+
+	for pt := range colors {
+		if reserved.has(pt) {
+			delete(colors, pt)
+		}
+	}
+	if len(colors) > 0 {
+		laid, err := placeLayer(p.bounds, colors, &Options{Bricks: p.shapes(p, colors)}, false, nil)
+		if err != nil {
+			return nil, err
+		}
+		laid.bricks.each(func(pos image.Point, brick *Brick) {
+			ret.bricks.set(pos, brick)
+		})
+	}
+	for pos, brick := range technic {
+		b := brick
+		ret.bricks.set(pos, &b)
+	}
+	p.copyTags(ret, nil)
+	for pos := range technic {
+		ret.addTag(pos, MOUNT_TAG)
+	}
+	return ret, nil
+}
+
+// drawHoles marks the holes of a Technic brick, which sit between its studs,
+// with rings in a contrasting color.
+func drawHoles(out draw.Image, pos image.Point, brick *Brick, scale int) {
+	step := image.Point{1, 0}
+	n := brick.Size.X
+	if brick.Size.Y > brick.Size.X {
+		step, n = image.Point{0, 1}, brick.Size.Y
+	}
+	ink := &image.Uniform{contrast(brick.Color.color)}
+	fill := &image.Uniform{brick.Color.color}
+	for i := 1; i < n; i++ {
+		center := pos.Add(step.Mul(i)).Mul(scale).Add(image.Point{1, 1}.Sub(step).Mul(scale / 2))
+		ring := &circle{center, scale / 4}
+		draw.DrawMask(out, ring.Bounds(), ink, image.ZP, ring, ring.Bounds().Min, draw.Over)
+		hole := &circle{center, scale/4 - 1 - scale/16}
+		draw.DrawMask(out, hole.Bounds(), fill, image.ZP, hole, hole.Bounds().Min, draw.Over)
+	}
+}
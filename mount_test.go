@@ -0,0 +1,58 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestMountingPlan(t *testing.T) {
+	p, err := NewPanel(blocks(32, 16, image.Rect(0, 0, 32, 1)), &Options{Width: 32, Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		spec MountSpec
+		want []image.Rectangle
+	}{
+		{"default", MountSpec{}, []image.Rectangle{
+			image.Rect(3, 0, 5, 1), image.Rect(11, 0, 13, 1), image.Rect(19, 0, 21, 1), image.Rect(27, 0, 29, 1),
+		}},
+		{"sides", MountSpec{Length: 4, Edges: LeftEdge | RightEdge}, []image.Rectangle{
+			image.Rect(0, 2, 1, 6), image.Rect(31, 2, 32, 6), image.Rect(0, 10, 1, 14), image.Rect(31, 10, 32, 14),
+		}},
+		{"bottom, wide apart", MountSpec{Length: 8, Interval: 20, Edges: BottomEdge}, []image.Rectangle{
+			image.Rect(2, 15, 10, 16), image.Rect(22, 15, 30, 16),
+		}},
+	} {
+		m, err := p.MountingPlan(tc.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var got []image.Rectangle
+		m.ForEach(func(pos image.Point, b Brick) {
+			if b.Technic {
+				got = append(got, image.Rectangle{pos, pos.Add(b.Size)})
+			}
+		})
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Technic bricks at %v, want %v", tc.name, got, tc.want)
+		}
+		// The studs keep their colors, the red top row included.
+		if !reflect.DeepEqual(studColors(m), studColors(p)) {
+			t.Errorf("%s: mounting changed the colors of the panel", tc.name)
+		}
+	}
+	if _, err := p.MountingPlan(MountSpec{Length: 3}); err == nil {
+		t.Error("mounted with 1x3 Technic bricks")
+	}
+	art, err := NewArtPanel(gradient(48, 48), &Options{Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := art.MountingPlan(MountSpec{}); err == nil {
+		t.Error("mounted a panel of round plates")
+	}
+}
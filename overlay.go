@@ -0,0 +1,120 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+var (
+	// Translucent colors, for Options.Overlays. Their alpha approximates
+	// how much light they let through.
+	TRANS_CLEAR  = Color{"Transparent (#40)", color.NRGBA{236, 236, 236, 64}, 40, 12, 47}
+	TRANS_RED    = Color{"Tr. red (#41)", color.NRGBA{205, 84, 75, 128}, 41, 17, 36}
+	TRANS_BLUE   = Color{"Tr. blue (#43)", color.NRGBA{123, 182, 232, 128}, 43, 14, 33}
+	TRANS_YELLOW = Color{"Tr. yellow (#44)", color.NRGBA{247, 241, 141, 128}, 44, 19, 46}
+	TRANS_GREEN  = Color{"Tr. green (#48)", color.NRGBA{132, 182, 141, 128}, 48, 20, 34}
+
+	TRANS_COLORS = []Color{TRANS_CLEAR, TRANS_RED, TRANS_BLUE, TRANS_YELLOW, TRANS_GREEN}
+)
+
+// overlayPart is the design number of the 1x1 round plate used in overlays.
+const overlayPart = "4073"
+
+// seenThrough returns the color of base under a translucent overlay.
+func seenThrough(base, overlay color.Color) color.Color {
+	b := color.NRGBAModel.Convert(base).(color.NRGBA)
+	o := color.NRGBAModel.Convert(overlay).(color.NRGBA)
+	a := float64(o.A) / 255
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x)*(1-a) + float64(y)*a + 0.5)
+	}
+	return color.NRGBA{mix(b.R, o.R), mix(b.G, o.G), mix(b.B, o.B), 255}
+}
+
+// withOverlays extends palette with every color seen through every overlay.
+// The color at index len(palette)*(i+1)+j is palette[j] under overlays[i].
+func withOverlays(palette color.Palette, overlays []Color) (color.Palette, error) {
+	result := append(color.Palette(nil), palette...)
+	for _, overlay := range overlays {
+		if _, _, _, a := overlay.color.RGBA(); a == 0xffff {
+			return nil, fmt.Errorf("lego: overlay color %s is not translucent", overlay.name)
+		}
+		for _, base := range palette {
+			result = append(result, seenThrough(base, overlay.color))
+		}
+	}
+	if len(result) > 256 {
+		return nil, fmt.Errorf("lego: %d colors and %d overlays make too many combinations",
+			len(palette), len(overlays))
+	}
+	return result, nil
+}
+
+// splitOverlays turns every pixel of dst using an extended palette color
+// into its base color, and returns where overlays go. It then drops the
+// extended colors from the palette.
+func splitOverlays(dst *image.Paletted, n int, overlays []Color) map[image.Point]Color {
+	result := make(map[image.Point]Color)
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := int(dst.ColorIndexAt(x, y))
+			if i < n {
+				continue
+			}
+			result[image.Point{x, y}] = overlays[i/n-1]
+			dst.SetColorIndex(x, y, uint8(i%n))
+		}
+	}
+	dst.Palette = dst.Palette[:n]
+	return result
+}
+
+// Overlay returns the translucent 1x1 round plates placed over the bricks,
+// by stud, when the panel was built with Options.Overlays.
+func (p *Panel) Overlay() map[image.Point]Color {
+	result := make(map[image.Point]Color, len(p.overlay))
+	for pt, c := range p.overlay {
+		result[pt] = c
+	}
+	return result
+}
+
+// CountOverlay returns how many 1x1 round plates of each translucent color
+// the overlay needs.
+func (p *Panel) CountOverlay() map[Color]int {
+	result := make(map[Color]int)
+	for _, c := range p.overlay {
+		result[c]++
+	}
+	return result
+}
+
+// overlayPositions returns the studs with an overlay, in row-major order.
+func (p *Panel) overlayPositions() []image.Point {
+	var result []image.Point
+	for pt := range p.overlay {
+		result = append(result, pt)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Y != result[j].Y {
+			return result[i].Y < result[j].Y
+		}
+		return result[i].X < result[j].X
+	})
+	return result
+}
+
+// drawOverlay draws the translucent plates over a rendered panel.
+func (p *Panel) drawOverlay(out draw.Image, scale int) {
+	for _, pt := range p.overlayPositions() {
+		c := p.overlay[pt]
+		plate := &circle{pt.Mul(scale).Add(image.Point{scale / 2, scale / 2}), scale * 9 / 20}
+		draw.DrawMask(out, plate.Bounds(), &image.Uniform{c.color}, image.ZP, plate,
+			plate.Bounds().Min, draw.Over)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"github.com/nfnt/resize"
+	"image"
+	"image/color"
+	"math"
+)
+
+// CHOOSE_PALETTE_SAMPLES is the number of pixels, roughly, that
+// ChoosePalette looks at; larger images are scaled down first.
+var CHOOSE_PALETTE_SAMPLES = 64 * 64
+
+// ChoosePalette returns the bricks of candidates in the maxColors colors
+// that reproduce img best. Colors are picked one at a time, each the one
+// that lowers the error the most, and then refined k-means style, where
+// every cluster of pixels moves to the candidate color closest to it as a
+// whole. If candidates has no more than maxColors colors, or maxColors is
+// not positive, it is returned as is.
+func ChoosePalette(img image.Image, candidates []*Brick, maxColors int) []*Brick {
+	var colors []Color
+	seen := make(map[Color]bool)
+	for _, brick := range candidates {
+		if !seen[brick.Color] {
+			seen[brick.Color] = true
+			colors = append(colors, brick.Color)
+		}
+	}
+	b := img.Bounds()
+	if maxColors <= 0 || len(colors) <= maxColors || b.Empty() {
+		return candidates
+	}
+
+	// Weigh every distinct sampled color by how often it appears.
+	if b.Dx()*b.Dy() > CHOOSE_PALETTE_SAMPLES {
+		f := math.Sqrt(float64(CHOOSE_PALETTE_SAMPLES) / float64(b.Dx()*b.Dy()))
+		img = resize.Resize(uint(math.Ceil(float64(b.Dx())*f)), uint(math.Ceil(float64(b.Dy())*f)),
+			img, resize.Bilinear)
+		b = img.Bounds()
+	}
+	index := make(map[color.NRGBA]int)
+	var pixels [][3]float64
+	var weights []float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i, ok := index[c]
+			if !ok {
+				i = len(pixels)
+				index[c] = i
+				pixels = append(pixels, lab(c))
+				weights = append(weights, 0)
+			}
+			weights[i]++
+		}
+	}
+	gamut := make([][3]float64, len(colors))
+	for i, c := range colors {
+		gamut[i] = lab(c.color)
+	}
+	// dist[i][j] is the squared distance from pixel i to color j.
+	dist := make([][]float64, len(pixels))
+	for i, p := range pixels {
+		dist[i] = make([]float64, len(gamut))
+		for j, g := range gamut {
+			dist[i][j] = (p[0]-g[0])*(p[0]-g[0]) + (p[1]-g[1])*(p[1]-g[1]) + (p[2]-g[2])*(p[2]-g[2])
+		}
+	}
+
+	best := make([]float64, len(pixels))
+	for i := range best {
+		best[i] = math.Inf(1)
+	}
+	chosen := make([]int, 0, maxColors)
+	used := make([]bool, len(colors))
+	for len(chosen) < maxColors {
+		pick, lowest := -1, math.Inf(1)
+		for j := range colors {
+			if used[j] {
+				continue
+			}
+			total := 0.0
+			for i := range pixels {
+				total += weights[i] * math.Min(best[i], dist[i][j])
+			}
+			if total < lowest {
+				pick, lowest = j, total
+			}
+		}
+		used[pick] = true
+		chosen = append(chosen, pick)
+		for i := range pixels {
+			best[i] = math.Min(best[i], dist[i][pick])
+		}
+	}
+
+	for round := 0; round < 10; round++ {
+		clusters := make([][]int, len(chosen))
+		for i := range pixels {
+			k := 0
+			for c := range chosen {
+				if dist[i][chosen[c]] < dist[i][chosen[k]] {
+					k = c
+				}
+			}
+			clusters[k] = append(clusters[k], i)
+		}
+		moved := false
+		for k, cluster := range clusters {
+			cost := func(j int) float64 {
+				total := 0.0
+				for _, i := range cluster {
+					total += weights[i] * dist[i][j]
+				}
+				return total
+			}
+			current := cost(chosen[k])
+			for j := range colors {
+				if !used[j] && cost(j) < current {
+					used[chosen[k]], used[j] = false, true
+					chosen[k], current = j, cost(j)
+					moved = true
+				}
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	keep := make(map[Color]bool)
+	for _, j := range chosen {
+		keep[colors[j]] = true
+	}
+	var result []*Brick
+	for _, brick := range candidates {
+		if keep[brick.Color] {
+			result = append(result, brick)
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"sort"
+)
+
+// PartitionOptions configures Panel.Partition.
+type PartitionOptions struct {
+	// Columns splits the panel into vertical strips instead of horizontal
+	// ones.
+	Columns bool
+	// Tolerance is how far, as a fraction of the fair share, a region's
+	// brick count may stray so it ends along a whole row (or column) of
+	// bricks. It defaults to 0.1; a negative value cuts at the exact
+	// counts.
+	Tolerance float64
+}
+
+// Partition splits the panel into n contiguous regions with about the same
+// number of bricks each, for a group build. Regions are strips of whole
+// bricks, taken in row-major order (column-major with Columns). Every region
+// is a panel the size of the whole one holding only its bricks and their
+// overlay plates, so its CountBricks and WriteBOM give its kit.
+func (p *Panel) Partition(n int, opts PartitionOptions) ([]*Panel, error) {
+	positions := p.positions()
+	if n <= 0 || n > len(positions) {
+		return nil, errors.New("lego: cannot split the panel in that many regions")
+	}
+	// major is the row, or column, of a position.
+	major := func(pt image.Point) int { return pt.Y }
+	if opts.Columns {
+		major = func(pt image.Point) int { return pt.X }
+		sort.SliceStable(positions, func(i, j int) bool {
+			if positions[i].X != positions[j].X {
+				return positions[i].X < positions[j].X
+			}
+			return positions[i].Y < positions[j].Y
+		})
+	}
+	tolerance := opts.Tolerance
+	if tolerance == 0 {
+		tolerance = 0.1
+	}
+
+	// Cut where a row starts, if there is one close enough.
+	cuts := []int{0}
+	for k := 1; k < n; k++ {
+		ideal := k * len(positions) / n
+		slack := int(tolerance * float64(len(positions)) / float64(n))
+		cut := ideal
+		for d := 0; d <= slack; d++ {
+			if i := ideal - d; i > cuts[k-1] && major(positions[i]) != major(positions[i-1]) {
+				cut = i
+				break
+			}
+			if i := ideal + d; i > cuts[k-1] && i < len(positions) && major(positions[i]) != major(positions[i-1]) {
+				cut = i
+				break
+			}
+		}
+		// Leave at least a brick for this region and each of the next.
+		if cut <= cuts[k-1] {
+			cut = cuts[k-1] + 1
+		}
+		if last := len(positions) - (n - k); cut > last {
+			cut = last
+		}
+		cuts = append(cuts, cut)
+	}
+	cuts = append(cuts, len(positions))
+
+	result := make([]*Panel, n)
+	region := make(map[image.Point]int)
+	for k := range result {
+		result[k] = newPanel(p.bounds)
+		result[k].round = p.round
+		for _, pos := range positions[cuts[k]:cuts[k+1]] {
+			brick := *p.bricks.at(pos)
+			result[k].bricks.set(pos, &brick)
+			region[pos] = k
+		}
+	}
+	for _, r := range result {
+		p.copyTags(r, nil)
+	}
+	cells := p.cells()
+	for _, pt := range p.overlayPositions() {
+		pos, _ := cells.at(pt)
+		r := result[region[pos]]
+		if r.overlay == nil {
+			r.overlay = make(map[image.Point]Color)
+		}
+		r.overlay[pt] = p.overlay[pt]
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	p, err := NewPanel(gradient(64, 48), &Options{Width: 32, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole := studColors(p)
+	bricks := 0
+	for _, n := range p.CountBricks() {
+		bricks += n
+	}
+	for _, tc := range []struct {
+		name string
+		n    int
+		opts PartitionOptions
+	}{
+		{"one", 1, PartitionOptions{}},
+		{"rows", 3, PartitionOptions{}},
+		{"columns", 5, PartitionOptions{Columns: true}},
+		{"exact", 7, PartitionOptions{Tolerance: -1}},
+	} {
+		regions, err := p.Partition(tc.n, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(regions) != tc.n {
+			t.Fatalf("%s: %d regions", tc.name, len(regions))
+		}
+		// The regions cover the panel exactly, without overlapping.
+		covered := make(map[image.Point]bool)
+		min, max := bricks, 0
+		for i, r := range regions {
+			if r.Size() != p.Size() {
+				t.Errorf("%s: region %d is %v", tc.name, i, r.Size())
+			}
+			for pt, c := range studColors(r) {
+				if whole[pt] != c || covered[pt] {
+					t.Errorf("%s: region %d has %v at %v", tc.name, i, c, pt)
+				}
+				covered[pt] = true
+			}
+			count := 0
+			for _, n := range r.CountBricks() {
+				count += n
+			}
+			if count < min {
+				min = count
+			}
+			if count > max {
+				max = count
+			}
+		}
+		if len(covered) != len(whole) {
+			t.Errorf("%s: regions cover %d studs, want %d", tc.name, len(covered), len(whole))
+		}
+		if tc.opts.Tolerance < 0 && max-min > 1 {
+			t.Errorf("%s: regions have %d to %d bricks", tc.name, min, max)
+		}
+	}
+	for _, n := range []int{0, bricks + 1} {
+		if _, err := p.Partition(n, PartitionOptions{}); err == nil {
+			t.Errorf("split into %d regions", n)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+type partInfo struct {
+	number string  // LEGO design number.
+	price  float64 // Rough unit price in USD, bought individually.
+	mass   float64 // In grams, as listed by BrickLink.
+}
+
+// Known bricks, by canonical size.
+var brickParts = map[image.Point]partInfo{
+	{1, 1}: {"3005", 0.10, 0.43},
+	{1, 2}: {"3004", 0.12, 0.80},
+	{1, 3}: {"3622", 0.14, 1.15},
+	{1, 4}: {"3010", 0.17, 1.59},
+	{1, 6}: {"3009", 0.22, 2.35},
+	{1, 8}: {"3008", 0.26, 3.12},
+	{2, 2}: {"3003", 0.15, 1.16},
+	{2, 3}: {"3002", 0.18, 1.68},
+	{2, 4}: {"3001", 0.20, 2.32},
+	{2, 6}: {"2456", 0.28, 3.35},
+	{2, 8}: {"3007", 0.35, 4.42},
+}
+
+// Known plates, one third of the height of a brick, by canonical size.
+var plateParts = map[image.Point]partInfo{
+	{1, 1}: {"3024", 0.07, 0.18},
+	{1, 2}: {"3023", 0.08, 0.33},
+	{1, 3}: {"3623", 0.09, 0.45},
+	{1, 4}: {"3710", 0.10, 0.59},
+	{1, 6}: {"3666", 0.13, 0.87},
+	{1, 8}: {"3460", 0.16, 1.15},
+	{2, 2}: {"3022", 0.09, 0.52},
+	{2, 3}: {"3021", 0.11, 0.74},
+	{2, 4}: {"3020", 0.13, 0.95},
+	{2, 6}: {"3795", 0.17, 1.42},
+	{2, 8}: {"3034", 0.22, 1.87},
+}
+
+// Known Technic bricks with holes, by canonical size.
+var technicParts = map[image.Point]partInfo{
+	{1, 2}: {"3700", 0.15, 0.77},
+	{1, 4}: {"3701", 0.21, 1.41},
+	{1, 6}: {"3894", 0.29, 2.07},
+	{1, 8}: {"3702", 0.36, 2.72},
+}
+
+type shape struct {
+	size image.Point
+	mask uint64
+}
+
+// Known bricks with a Mask, by canonical shape.
+var shapedParts = map[shape]partInfo{
+	{image.Point{2, 2}, CORNER}: {"2357", 0.20, 1.52},
+}
+
+// Mass of the 1x1 round plates used in overlays, in grams.
+const overlayMass = 0.14
+
+// The 1x1 round plate of overlays and LEGO Art panels.
+var roundPlatePart = partInfo{overlayPart, 0.06, overlayMass}
+
+func (b Brick) part() partInfo {
+	c := b.canonical()
+	if c.Mask != 0 {
+		return shapedParts[shape{c.Size, c.Mask}]
+	}
+	if c.Technic {
+		return technicParts[c.Size]
+	}
+	return brickParts[c.Size]
+}
+
+// PartNumber returns the LEGO design number of the brick, or "" if unknown.
+func (b Brick) PartNumber() string {
+	return b.part().number
+}
+
+// BrickForPart returns the brick with the given design number and color, in
+// canonical orientation.
+func BrickForPart(number string, c Color) (Brick, bool) {
+	for size, part := range brickParts {
+		if part.number == number {
+			return Brick{size, c, 0, false}, true
+		}
+	}
+	for size, part := range technicParts {
+		if part.number == number {
+			return Brick{size, c, 0, true}, true
+		}
+	}
+	for s, part := range shapedParts {
+		if part.number == number {
+			return Brick{s.size, c, s.mask, false}, true
+		}
+	}
+	return Brick{}, false
+}
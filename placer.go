@@ -0,0 +1,161 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ColorGrid is what a Placer covers with bricks: the brick color of every
+// stud of the panel, once the image is resized and mapped to the palette.
+type ColorGrid struct {
+	bounds image.Rectangle
+	colors []Color
+}
+
+// Bounds returns the studs of the grid.
+func (g ColorGrid) Bounds() image.Rectangle {
+	return g.bounds
+}
+
+// At returns the color of the stud at pt, and false if it must stay empty,
+// such as outside Options.Canvas, or if pt is outside the grid.
+func (g ColorGrid) At(pt image.Point) (Color, bool) {
+	if !pt.In(g.bounds) {
+		return Color{}, false
+	}
+	c := g.colors[(pt.Y-g.bounds.Min.Y)*g.bounds.Dx()+pt.X-g.bounds.Min.X]
+	return c, c.color != nil
+}
+
+// paletted returns the grid as an image of the brick colors, with holes
+// in a color that has none, and the brick color of every palette color.
+func (g ColorGrid) paletted() (*image.Paletted, map[color.Color]Color, error) {
+	palette := color.Palette{color.Transparent}
+	index := make(map[Color]uint8)
+	m := make(map[color.Color]Color)
+	dst := image.NewPaletted(g.bounds, nil)
+	for i, c := range g.colors {
+		if c.color == nil {
+			continue
+		}
+		j, ok := index[c]
+		if !ok {
+			if len(palette) == 256 {
+				return nil, nil, errors.New("lego: too many colors in the grid")
+			}
+			j = uint8(len(palette))
+			index[c] = j
+			palette = append(palette, c.color)
+			m[c.color] = c
+		}
+		dst.Pix[i] = j
+	}
+	dst.Palette = palette
+	return dst, m, nil
+}
+
+// Layout is a placement of bricks, by the position of their top-left stud,
+// as in Panel.ForEach.
+type Layout map[image.Point]Brick
+
+// Placer covers a grid of colors with bricks. Every stud with a color must be
+// covered by exactly one brick of that color, and bricks must be among those
+// given, in either orientation, and stay off empty studs. Set
+// Options.Placer to build panels with another tiling algorithm than the
+// greedy one; frames are always laid by the greedy algorithm.
+type Placer interface {
+	Place(grid ColorGrid, bricks []*Brick) (Layout, error)
+}
+
+// GreedyPlacer is the placement NewPanel uses by default: it goes over the
+// studs in row-major order and puts the largest brick that fits on each one
+// not covered yet. Its fields are like those of Options.
+type GreedyPlacer struct {
+	MaxInventory Inventory
+	BrickWeights map[Brick]float64
+	Seed         int64
+}
+
+func (g GreedyPlacer) Place(grid ColorGrid, bricks []*Brick) (Layout, error) {
+	dst, m, err := grid.paletted()
+	if err != nil {
+		return nil, err
+	}
+	opt := &Options{Bricks: bricks, MaxInventory: g.MaxInventory, BrickWeights: g.BrickWeights, Seed: g.Seed}
+	p, err := place(context.Background(), dst, m, opt, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	layout := make(Layout, p.bricks.len())
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		layout[pos] = *brick
+	})
+	return layout, nil
+}
+
+// placeWith covers dst, as place does, with opt.Placer, and checks the
+// layout it returns.
+func placeWith(ctx context.Context, dst *image.Paletted, m map[color.Color]Color, opt *Options, progress func(done, total int)) (*Panel, error) {
+	b := dst.Bounds()
+	grid := ColorGrid{bounds: b, colors: make([]Color, 0, b.Dx()*b.Dy())}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := m[dst.At(x, y)]
+			if s, ok := opt.Substitutions[c]; ok {
+				c = s
+			}
+			grid.colors = append(grid.colors, c)
+		}
+	}
+	bricks := substituteBricks(opt.Bricks, opt.Substitutions)
+	layout, err := opt.Placer.Place(grid, bricks)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[Brick]bool)
+	for _, brick := range bricks {
+		allowed[brick.canonical()] = true
+	}
+	ret := newPanel(b)
+	covered := newStudSet(b)
+	for pos, brick := range layout {
+		if !allowed[brick.canonical()] {
+			return nil, fmt.Errorf("lego: placer used %v, which is not among the bricks", brick)
+		}
+		min := pos.Sub(brick.anchor())
+		for y := 0; y < brick.Size.Y; y++ {
+			for x := 0; x < brick.Size.X; x++ {
+				pt := min.Add(image.Point{x, y})
+				if !brick.Covers(image.Point{x, y}) {
+					continue
+				}
+				if c, ok := grid.At(pt); !ok || c != brick.Color || covered.has(pt) {
+					return nil, fmt.Errorf("lego: placer put %v at %v over studs it can't cover", brick, pos)
+				}
+				covered.add(pt)
+			}
+		}
+		placed := brick
+		ret.bricks.set(pos, &placed)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			pt := image.Point{x, y}
+			if _, ok := grid.At(pt); ok && !covered.has(pt) {
+				return nil, fmt.Errorf("lego: placer left %v uncovered", pt)
+			}
+		}
+	}
+	if progress != nil {
+		progress(b.Dx()*b.Dy(), b.Dx()*b.Dy())
+	}
+	return ret, nil
+}
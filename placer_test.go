@@ -0,0 +1,99 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+// editPlacer lays 1x1 bricks on every stud with a color, then lets edit
+// change the layout.
+type editPlacer func(grid ColorGrid, l Layout)
+
+func (e editPlacer) Place(grid ColorGrid, bricks []*Brick) (Layout, error) {
+	l := make(Layout)
+	b := grid.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c, ok := grid.At(image.Point{x, y}); ok {
+				l[image.Point{x, y}] = Brick{image.Point{1, 1}, c, 0, false}
+			}
+		}
+	}
+	if e != nil {
+		e(grid, l)
+	}
+	return l, nil
+}
+
+type failingPlacer struct{}
+
+func (failingPlacer) Place(grid ColorGrid, bricks []*Brick) (Layout, error) {
+	return nil, errors.New("lego: no layout")
+}
+
+func TestPlacerValidation(t *testing.T) {
+	other := func(c Color) Color {
+		if c == WHITE {
+			return BLACK
+		}
+		return WHITE
+	}
+	for _, tc := range []struct {
+		name   string
+		placer Placer
+		ok     bool
+	}{
+		{"ones", editPlacer(nil), true},
+		{"placer error", failingPlacer{}, false},
+		{"brick not in the palette", editPlacer(func(grid ColorGrid, l Layout) {
+			c, _ := grid.At(image.ZP)
+			l[image.ZP] = Brick{image.Point{1, 1}, c, 0, true}
+		}), false},
+		{"wrong color", editPlacer(func(grid ColorGrid, l Layout) {
+			c, _ := grid.At(image.ZP)
+			l[image.ZP] = Brick{image.Point{1, 1}, other(c), 0, false}
+		}), false},
+		{"overlap", editPlacer(func(grid ColorGrid, l Layout) {
+			c, _ := grid.At(image.ZP)
+			l[image.ZP] = Brick{image.Point{2, 1}, c, 0, false}
+		}), false},
+		{"uncovered stud", editPlacer(func(grid ColorGrid, l Layout) {
+			delete(l, image.Point{3, 2})
+		}), false},
+		{"outside the grid", editPlacer(func(grid ColorGrid, l Layout) {
+			c, _ := grid.At(image.ZP)
+			l[image.Point{-1, 0}] = Brick{image.Point{1, 1}, c, 0, false}
+		}), false},
+	} {
+		// A single color, so that the overlap and outside cases only
+		// break the rule they are named after.
+		img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		draw.Draw(img, img.Bounds(), &image.Uniform{WHITE.color}, image.ZP, draw.Src)
+		p, err := NewPanel(img, &Options{Width: 10, Bricks: BASIC_BRICKS, Placer: tc.placer})
+		if tc.ok != (err == nil) {
+			t.Errorf("%s: got error %v", tc.name, err)
+		}
+		if err == nil && p.bricks.len() != 100 {
+			t.Errorf("%s: %d bricks, want 100", tc.name, p.bricks.len())
+		}
+	}
+}
+
+func TestGreedyPlacer(t *testing.T) {
+	for _, dither := range []bool{false, true} {
+		want, err := NewPanel(gradient(100, 50), &Options{Width: 30, Bricks: ALL_BRICKS, Dither: dither})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := NewPanel(gradient(100, 50), &Options{Width: 30, Bricks: ALL_BRICKS, Dither: dither, Placer: GreedyPlacer{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !samePanels(want, got) {
+			t.Errorf("dither %v: GreedyPlacer differs from the default placement", dither)
+		}
+	}
+}
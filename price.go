@@ -0,0 +1,58 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// PriceList maps bricks, in canonical orientation, to their unit price. The
+// 1x1 round plates of LEGO Art panels and overlays are priced by color,
+// under the keys RoundPlate returns.
+type PriceList map[Brick]float64
+
+// RoundPlate returns the key of the 1x1 round plates of color c in a
+// PriceList. Its size is zero, so it never stands for a brick.
+func RoundPlate(c Color) Brick {
+	return Brick{image.ZP, c, 0, false}
+}
+
+// price returns the unit price of brick or, if round is set, of a 1x1 round
+// plate of its color.
+func (l PriceList) price(brick Brick, round bool) float64 {
+	if round {
+		return l[RoundPlate(brick.Color)]
+	}
+	return l[brick.canonical()]
+}
+
+func estimatePrices(bricks []*Brick, plates []Color) PriceList {
+	result := make(PriceList)
+	for _, brick := range bricks {
+		if part := brick.part(); part.number != "" {
+			result[brick.canonical()] = part.price
+		}
+		result[RoundPlate(brick.Color)] = roundPlatePart.price
+	}
+	for _, c := range plates {
+		result[RoundPlate(c)] = roundPlatePart.price
+	}
+	return result
+}
+
+// ESTIMATED_PRICES is a rough price list for EXTENDED_BRICKS, and round
+// plates in their colors and TRANS_COLORS, good enough to compare panels but
+// not to budget a purchase.
+var ESTIMATED_PRICES = estimatePrices(EXTENDED_BRICKS, TRANS_COLORS)
+
+// Cost returns the total price of the bricks and overlay plates in the
+// panel. Those missing from prices are not counted.
+func (p *Panel) Cost(prices PriceList) float64 {
+	total := 0.0
+	for brick, n := range p.CountBricks() {
+		total += prices.price(brick, p.round) * float64(n)
+	}
+	for c, n := range p.CountOverlay() {
+		total += prices[RoundPlate(c)] * float64(n)
+	}
+	return total
+}
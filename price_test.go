@@ -0,0 +1,60 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestRoundPlatePrices(t *testing.T) {
+	art, err := NewArtPanel(gradient(96, 96), &Options{Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlaid, err := NewPanel(gradient(96, 64), &Options{Width: 24, Bricks: BASIC_BRICKS, Overlays: TRANS_COLORS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlaid.CountOverlay()) == 0 {
+		t.Fatal("no overlay plates to price")
+	}
+	for _, tc := range []struct {
+		name   string
+		p      *Panel
+		plates int
+	}{
+		{"art", art, ART_SIZE * ART_SIZE},
+		{"overlays", overlaid, len(overlaid.Overlay())},
+	} {
+		var buf bytes.Buffer
+		if err := tc.p.WritePricedBOM(&buf, CSV, ESTIMATED_PRICES); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		plates, total := 0, 0.0
+		for _, row := range rows[1:] {
+			n, _ := strconv.Atoi(row[2])
+			unit, _ := strconv.ParseFloat(row[3], 64)
+			price, _ := strconv.ParseFloat(row[4], 64)
+			if row[0] == "1x1 round plate" {
+				plates += n
+				if unit != roundPlatePart.price {
+					t.Errorf("%s: %s round plates cost %v, want %v", tc.name, row[1], unit, roundPlatePart.price)
+				}
+			}
+			total += price
+		}
+		if plates != tc.plates {
+			t.Errorf("%s: BOM lists %d round plates, want %d", tc.name, plates, tc.plates)
+		}
+		if cost := tc.p.Cost(ESTIMATED_PRICES); math.Abs(cost-total) > 0.005*float64(len(rows)) {
+			t.Errorf("%s: Cost is %.2f, but the BOM adds up to %.2f", tc.name, cost, total)
+		}
+	}
+}
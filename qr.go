@@ -0,0 +1,126 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"rsc.io/qr"
+)
+
+// QR_QUIET_ZONE is the minimum margin around a QR code, in modules.
+var QR_QUIET_ZONE = 4
+
+// NewQRPanel builds a panel showing content as a QR code, with dark modules
+// in Options.Foreground (default BLACK) over Options.Background (default
+// WHITE).
+//
+// Each module takes Width / (size + 2*QR_QUIET_ZONE) studs, using the smaller
+// of Width and Height if both are set, and the code is centered in the panel.
+// Without either, modules are one stud. An error is returned if the colors
+// don't have enough contrast, if the code doesn't fit with its quiet zone,
+// or if the modules don't read back from the bricks laid, such as when a
+// Placer or Substitutions change their colors. These checks don't decode
+// the code, so they can't vouch that a given scanner reads it.
+func NewQRPanel(content string, opt *Options) (*Panel, error) {
+	fg, bg, m, err := twoColors(opt)
+	if err != nil {
+		return nil, err
+	}
+	if !contrasts(fg, bg) {
+		return nil, fmt.Errorf("lego: not enough contrast between %s and %s for a QR code",
+			fg.name, bg.name)
+	}
+	code, err := qr.Encode(content, qr.M)
+	if err != nil {
+		return nil, fmt.Errorf("lego: %v", err)
+	}
+
+	modules := code.Size + 2*QR_QUIET_ZONE
+	side := int(opt.Width)
+	if opt.Height > 0 && (side == 0 || int(opt.Height) < side) {
+		side = int(opt.Height)
+	}
+	if side == 0 {
+		side = modules
+	}
+	scale := side / modules
+	if scale == 0 {
+		return nil, fmt.Errorf("lego: QR code needs %dx%d studs, panel is %dx%d",
+			modules, modules, side, side)
+	}
+	if err := checkSize(image.Point{side, side}, opt); err != nil {
+		return nil, err
+	}
+
+	dst := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{bg.color, fg.color})
+	offset := (side - code.Size*scale) / 2
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			mx, my := x-offset, y-offset
+			if mx < 0 || my < 0 || mx >= code.Size*scale || my >= code.Size*scale {
+				continue
+			}
+			if code.Black(mx/scale, my/scale) {
+				dst.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	p, err := place(context.Background(), dst, m, opt, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkModules(p, code, scale, offset, fg, bg); err != nil {
+		return nil, err
+	}
+	return p.framed(opt)
+}
+
+// contrasts returns whether dark is dark enough against light for scanners.
+func contrasts(dark, light Color) bool {
+	return brightness(light.color)-brightness(dark.color) >= 0.4
+}
+
+// checkModules reads the modules of code back from the bricks of p, where
+// they are scale studs wide from offset, and returns an error unless every
+// stud of a module contrasts with the light or dark modules as the code
+// wants, and the quiet zone around them is all light.
+func checkModules(p *Panel, code *qr.Code, scale, offset int, dark, light Color) error {
+	colors := make(map[image.Point]Color)
+	p.cells().each(func(pt, pos image.Point) {
+		colors[pt] = p.bricks.at(pos).Color
+	})
+	quiet := QR_QUIET_ZONE * scale
+	zone := image.Rect(offset-quiet, offset-quiet, offset+code.Size*scale+quiet, offset+code.Size*scale+quiet)
+	if !zone.In(p.bounds) {
+		return fmt.Errorf("lego: no room for a quiet zone of %d modules", QR_QUIET_ZONE)
+	}
+	for y := zone.Min.Y; y < zone.Max.Y; y++ {
+		for x := zone.Min.X; x < zone.Max.X; x++ {
+			pt := image.Point{x, y}
+			module := pt.Sub(image.Point{offset, offset}).Div(scale)
+			inCode := x >= offset && y >= offset && module.X < code.Size && module.Y < code.Size
+			c, ok := colors[pt]
+			if inCode && code.Black(module.X, module.Y) {
+				ok = ok && contrasts(c, light)
+			} else {
+				ok = ok && contrasts(dark, c)
+			}
+			if ok {
+				continue
+			}
+			if !inCode {
+				return fmt.Errorf("lego: QR code quiet zone is not light at stud %v", pt)
+			}
+			return fmt.Errorf("lego: QR module %v does not read back from the bricks at stud %v", module, pt)
+		}
+	}
+	return nil
+}
+
+// brightness returns the perceived brightness of c, between 0 and 1.
+func brightness(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return float64(299*r+587*g+114*b) / (1000 * 0xffff)
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+func TestNewQRPanel(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opt  Options
+		ok   bool
+	}{
+		{"one stud per module", Options{Bricks: BASIC_BRICKS}, true},
+		{"scaled", Options{Width: 64, Bricks: BASIC_BRICKS}, true},
+		{"smaller side", Options{Width: 100, Height: 64, Bricks: BASIC_BRICKS}, true},
+		{"light on dark", Options{Foreground: BRIGHT_BLUE, Background: BRIGHT_YELLOW, Bricks: BASIC_BRICKS}, true},
+		{"framed", Options{Bricks: BASIC_BRICKS, Frame: FrameSpec{Width: 1, Color: BLACK}}, true},
+		{"low contrast", Options{Foreground: BRIGHT_YELLOW, Bricks: BASIC_BRICKS}, false},
+		{"too small", Options{Width: 10, Bricks: BASIC_BRICKS}, false},
+		{"no bricks in the color", Options{Foreground: DARK_STONE_GREY, Bricks: BASIC_BRICKS}, false},
+		{"substituted modules", Options{Bricks: BASIC_BRICKS, Substitutions: map[Color]Color{BLACK: BRIGHT_YELLOW}}, false},
+	} {
+		p, err := NewQRPanel("https://example.com", &tc.opt)
+		if tc.ok != (err == nil) {
+			t.Errorf("%s: got error %v", tc.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if s := p.Size(); s.X != s.Y || s.X < 21+2*QR_QUIET_ZONE {
+			t.Errorf("%s: panel is %v", tc.name, s)
+		}
+		if n := p.cells().len(); n != p.Size().X*p.Size().Y {
+			t.Errorf("%s: %d of %v studs covered", tc.name, n, p.Size())
+		}
+		// The corners of the quiet zone are light.
+		light := tc.opt.Background
+		if light.color == nil {
+			light = WHITE
+		}
+		in := image.Rectangle{image.ZP, p.Size()}.Inset(tc.opt.Frame.Width)
+		if b, _, ok := p.BrickAt(in.Min); !ok || b.Color != light {
+			t.Errorf("%s: corner is %v, want %v", tc.name, b, light)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"github.com/nfnt/resize"
+	"image"
+	"image/color"
+	"math"
+)
+
+// QualityReport measures how well a panel reproduces its source image.
+type QualityReport struct {
+	MeanDeltaE float64 // Mean CIE76 color difference per stud.
+	MaxDeltaE  float64
+	SSIM       float64 // Structural similarity, up to 1 for identical images.
+	// BrickSizes counts the bricks of each size, in canonical orientation.
+	BrickSizes map[image.Point]int
+}
+
+// Quality compares the panel with src, resized to one pixel per stud. src
+// should be the image the panel was built from, cropped and padded the same
+// way if Crop or Fit changed it.
+func (p *Panel) Quality(src image.Image) QualityReport {
+	size := p.Size()
+	scaled := resize.Resize(uint(size.X), uint(size.Y), src, resize.Lanczos3)
+	seen := p.studColors()
+	report := QualityReport{SSIM: ssim(seen, scaled), BrickSizes: make(map[image.Point]int)}
+	report.MeanDeltaE, report.MaxDeltaE = colorError(seen, scaled)
+	for brick, n := range p.CountBricks() {
+		report.BrickSizes[brick.Size] += n
+	}
+	return report
+}
+
+// studColors returns an image with one pixel per stud, in the color of the
+// brick as seen through its overlay, if any.
+func (p *Panel) studColors() *image.NRGBA {
+	out := image.NewNRGBA(p.bounds)
+	p.cells().each(func(pt, pos image.Point) {
+		var c color.Color = p.bricks.at(pos).Color.color
+		if overlay, ok := p.overlay[pt]; ok {
+			c = seenThrough(c, overlay.color)
+		}
+		out.Set(pt.X, pt.Y, c)
+	})
+	return out
+}
+
+// lab converts c to CIE L*a*b* under a D65 white point.
+func lab(c color.Color) [3]float64 {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+	linear := func(v uint32) float64 {
+		f := float64(v) / 0xffff
+		if f <= 0.04045 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	lr, lg, lb := linear(r), linear(g), linear(b)
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// deltaE is the CIE76 color difference; about 2.3 is barely noticeable.
+func deltaE(a, b color.Color) float64 {
+	la, lb := lab(a), lab(b)
+	return math.Sqrt((la[0]-lb[0])*(la[0]-lb[0]) + (la[1]-lb[1])*(la[1]-lb[1]) +
+		(la[2]-lb[2])*(la[2]-lb[2]))
+}
+
+// colorError returns the mean and maximum deltaE between images of the same
+// size.
+func colorError(a, b image.Image) (mean, max float64) {
+	ba, bb := a.Bounds(), b.Bounds()
+	for y := 0; y < ba.Dy(); y++ {
+		for x := 0; x < ba.Dx(); x++ {
+			d := deltaE(a.At(ba.Min.X+x, ba.Min.Y+y), b.At(bb.Min.X+x, bb.Min.Y+y))
+			mean += d
+			max = math.Max(max, d)
+		}
+	}
+	return mean / float64(ba.Dx()*ba.Dy()), max
+}
+
+// ssim returns the mean structural similarity of the luminance of images of
+// the same size, over 7x7 windows: 1 for identical images, lower as
+// structure is lost.
+func ssim(a, b image.Image) float64 {
+	ba, bb := a.Bounds(), b.Bounds()
+	w, h := ba.Dx(), ba.Dy()
+	gray := func(img image.Image, min image.Point) []float64 {
+		out := make([]float64, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				out[y*w+x] = float64(color.GrayModel.Convert(img.At(min.X+x, min.Y+y)).(color.Gray).Y)
+			}
+		}
+		return out
+	}
+	x, y := gray(a, ba.Min), gray(b, bb.Min)
+	xx, yy, xy := make([]float64, w*h), make([]float64, w*h), make([]float64, w*h)
+	for i := range x {
+		xx[i], yy[i], xy[i] = x[i]*x[i], y[i]*y[i], x[i]*y[i]
+	}
+	const radius = 3
+	mx, my := boxBlur(x, w, h, radius), boxBlur(y, w, h, radius)
+	sxx, syy, sxy := boxBlur(xx, w, h, radius), boxBlur(yy, w, h, radius), boxBlur(xy, w, h, radius)
+	const c1, c2 = (0.01 * 255) * (0.01 * 255), (0.03 * 255) * (0.03 * 255)
+	total := 0.0
+	for i := range x {
+		vx, vy, cov := sxx[i]-mx[i]*mx[i], syy[i]-my[i]*my[i], sxy[i]-mx[i]*my[i]
+		total += (2*mx[i]*my[i] + c1) * (2*cov + c2) /
+			((mx[i]*mx[i] + my[i]*my[i] + c1) * (vx + vy + c2))
+	}
+	return total / float64(len(x))
+}
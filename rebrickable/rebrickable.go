@@ -0,0 +1,163 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+
+// Package rebrickable imports brick inventories from Rebrickable
+// (https://rebrickable.com), so panels can be checked against, or limited
+// to, the bricks a user owns.
+package rebrickable
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mrbubble/lego"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Rebrickable color IDs are LDraw color IDs.
+var colors = make(map[int]lego.Color)
+
+func init() {
+	for _, c := range lego.ALL_COLORS {
+		colors[c.LDrawID()] = c
+	}
+}
+
+// Part is an inventory line: Quantity parts of design Number in the color
+// with Rebrickable ID ColorID.
+type Part struct {
+	Number   string
+	ColorID  int
+	Quantity int
+}
+
+// Client accesses the Rebrickable API v3.
+type Client struct {
+	Key        string       // API key.
+	BaseURL    string       // Defaults to https://rebrickable.com.
+	HTTPClient *http.Client // Defaults to http.DefaultClient.
+}
+
+// NewClient returns a client using the given API key.
+func NewClient(key string) *Client {
+	return &Client{Key: key}
+}
+
+func (c *Client) url(path string) string {
+	base := c.BaseURL
+	if base == "" {
+		base = "https://rebrickable.com"
+	}
+	return strings.TrimRight(base, "/") + "/api/v3" + path
+}
+
+func (c *Client) do(req *http.Request, v interface{}) error {
+	req.Header.Set("Authorization", "key "+c.Key)
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rebrickable: %s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type page struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Quantity int `json:"quantity"`
+		Part     struct {
+			Number string `json:"part_num"`
+		} `json:"part"`
+		Color struct {
+			ID int `json:"id"`
+		} `json:"color"`
+	} `json:"results"`
+}
+
+// parts fetches every page of a part listing.
+func (c *Client) parts(path string) ([]Part, error) {
+	var result []Part
+	next := c.url(path) + "?page_size=1000"
+	for next != "" {
+		req, err := http.NewRequest("GET", next, nil)
+		if err != nil {
+			return nil, err
+		}
+		var p page
+		if err := c.do(req, &p); err != nil {
+			return nil, err
+		}
+		for _, r := range p.Results {
+			result = append(result, Part{r.Part.Number, r.Color.ID, r.Quantity})
+		}
+		next = p.Next
+	}
+	return result, nil
+}
+
+// Token logs in and returns the user token needed to read a user's parts.
+func (c *Client) Token(username, password string) (string, error) {
+	form := url.Values{"username": {username}, "password": {password}}
+	req, err := http.NewRequest("POST", c.url("/users/_token/"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp struct {
+		Token string `json:"user_token"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// UserParts returns all parts a user owns, from both their sets and their
+// part lists.
+func (c *Client) UserParts(userToken string) ([]Part, error) {
+	return c.parts("/users/" + url.PathEscape(userToken) + "/allparts/")
+}
+
+// PartListParts returns the parts in one of a user's part lists.
+func (c *Client) PartListParts(userToken string, listID int) ([]Part, error) {
+	return c.parts(fmt.Sprintf("/users/%s/partlists/%d/parts/", url.PathEscape(userToken), listID))
+}
+
+// SetParts returns the parts in a set, e.g. "31197-1".
+func (c *Client) SetParts(setNum string) ([]Part, error) {
+	return c.parts("/lego/sets/" + url.PathEscape(setNum) + "/parts/")
+}
+
+// UserInventory returns the bricks a user owns, ready to be used as
+// lego.Options.MaxInventory or checked with lego.Inventory.Missing.
+func (c *Client) UserInventory(userToken string) (lego.Inventory, error) {
+	parts, err := c.UserParts(userToken)
+	if err != nil {
+		return nil, err
+	}
+	return Inventory(parts), nil
+}
+
+// Inventory converts parts to a lego.Inventory, skipping parts and colors
+// the lego package does not use.
+func Inventory(parts []Part) lego.Inventory {
+	result := make(lego.Inventory)
+	for _, part := range parts {
+		c, ok := colors[part.ColorID]
+		if !ok {
+			continue
+		}
+		if brick, ok := lego.BrickForPart(part.Number, c); ok {
+			result[brick] += part.Quantity
+		}
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package rebrickable
+
+import (
+	"fmt"
+	"github.com/mrbubble/lego"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeAPI serves a few Rebrickable endpoints, with part listings split into
+// two pages.
+func fakeAPI(t *testing.T) *httptest.Server {
+	var srv *httptest.Server
+	pages := func(w http.ResponseWriter, r *http.Request, first, second string) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `{"next": null, "results": [%s]}`, second)
+			return
+		}
+		if got := r.URL.Query().Get("page_size"); got != "1000" {
+			t.Errorf("%s: page_size %q, want 1000", r.URL.Path, got)
+		}
+		fmt.Fprintf(w, `{"next": "%s%s?page=2", "results": [%s]}`, srv.URL, r.URL.Path, first)
+	}
+	line := func(number string, color, quantity int) string {
+		return fmt.Sprintf(`{"quantity": %d, "part": {"part_num": %q}, "color": {"id": %d}}`, quantity, number, color)
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "key secret" {
+			http.Error(w, "bad key", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("%s: Accept %q", r.URL.Path, r.Header.Get("Accept"))
+		}
+		switch r.URL.Path {
+		case "/api/v3/users/_token/":
+			if r.Method != "POST" || r.PostFormValue("username") != "bubble" || r.PostFormValue("password") != "hunter2" {
+				http.Error(w, "bad login", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"user_token": "t/ok"}`)
+		case "/api/v3/lego/sets/31197-1/parts/":
+			pages(w, r, line("3001", 4, 2)+","+line("3024", 15, 10), line("3005", 0, 5))
+		case "/api/v3/users/t/ok/allparts/":
+			// A part and a color the lego package does not use.
+			pages(w, r, line("3001", 4, 2)+","+line("99999", 4, 1), line("3005", 9999, 3)+","+line("3001", 4, 1))
+		case "/api/v3/users/t/ok/partlists/7/parts/":
+			pages(w, r, line("3700", 15, 4), "")
+		case "/api/v3/lego/sets/broken/parts/":
+			fmt.Fprint(w, `{"results": [`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := fakeAPI(t)
+	defer srv.Close()
+	c := &Client{Key: "secret", BaseURL: srv.URL + "/"}
+
+	parts, err := c.SetParts("31197-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Part{{"3001", 4, 2}, {"3024", 15, 10}, {"3005", 0, 5}}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("SetParts = %v, want %v", parts, want)
+	}
+	if parts, err = c.PartListParts("t/ok", 7); err != nil || !reflect.DeepEqual(parts, []Part{{"3700", 15, 4}}) {
+		t.Errorf("PartListParts = %v, %v", parts, err)
+	}
+
+	token, err := c.Token("bubble", "hunter2")
+	if err != nil || token != "t/ok" {
+		t.Fatalf("Token = %q, %v", token, err)
+	}
+	inv, err := c.UserInventory(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	brick, _ := lego.BrickForPart("3001", lego.BRIGHT_RED)
+	if want := (lego.Inventory{brick: 3}); !reflect.DeepEqual(inv, want) {
+		t.Errorf("UserInventory = %v, want %v", inv, want)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := fakeAPI(t)
+	defer srv.Close()
+	c := &Client{Key: "secret", BaseURL: srv.URL}
+	for _, tc := range []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"bad login", func() error { _, err := c.Token("bubble", "guess"); return err }, "401"},
+		{"no such set", func() error { _, err := c.SetParts("0000-1"); return err }, "404"},
+		{"bad key", func() error {
+			_, err := (&Client{Key: "wrong", BaseURL: srv.URL}).UserParts("t/ok")
+			return err
+		}, "401"},
+		{"bad JSON", func() error { _, err := c.SetParts("broken"); return err }, "EOF"},
+	} {
+		err := tc.call()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: error %v, want one mentioning %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	palettesMu sync.Mutex
+	palettes   = map[string][]*Brick{
+		"basic":     BASIC_BRICKS,
+		"advanced":  ADVANCED_BRICKS,
+		"all":       ALL_BRICKS,
+		"extended":  EXTENDED_BRICKS,
+		"greyscale": GREYSCALE_BRICKS,
+	}
+)
+
+// Palettes returns the named sets of bricks to build with, such as "basic"
+// for BASIC_BRICKS, for frontends that take the palette as a string. The
+// map is a copy, but the bricks must not be modified.
+func Palettes() map[string][]*Brick {
+	palettesMu.Lock()
+	defer palettesMu.Unlock()
+	result := make(map[string][]*Brick, len(palettes))
+	for name, bricks := range palettes {
+		result[name] = bricks
+	}
+	return result
+}
+
+// RegisterPalette adds a named set of bricks to Palettes, or replaces one.
+func RegisterPalette(name string, bricks []*Brick) {
+	palettesMu.Lock()
+	palettes[name] = bricks
+	palettesMu.Unlock()
+}
+
+// ColorByID returns the color, among ALL_COLORS and TRANS_COLORS, with the
+// given LEGO color ID.
+func ColorByID(id int) (Color, bool) {
+	for _, colors := range [][]Color{ALL_COLORS, TRANS_COLORS} {
+		for _, c := range colors {
+			if c.id == id {
+				return c, true
+			}
+		}
+	}
+	return Color{}, false
+}
+
+// ColorByName returns the color, among ALL_COLORS and TRANS_COLORS, with
+// the given name, ignoring case, with or without its ID as in
+// "Dark stone grey (#199)".
+func ColorByName(name string) (Color, bool) {
+	name = strings.TrimSpace(name)
+	for _, colors := range [][]Color{ALL_COLORS, TRANS_COLORS} {
+		for _, c := range colors {
+			short := c.name
+			if i := strings.LastIndex(short, " (#"); i >= 0 {
+				short = short[:i]
+			}
+			if strings.EqualFold(name, c.name) || strings.EqualFold(name, short) {
+				return c, true
+			}
+		}
+	}
+	return Color{}, false
+}
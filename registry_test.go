@@ -0,0 +1,61 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import "testing"
+
+func TestPalettes(t *testing.T) {
+	if got := Palettes()["basic"]; len(got) != len(BASIC_BRICKS) || got[0] != BASIC_BRICKS[0] {
+		t.Errorf(`Palettes()["basic"] is not BASIC_BRICKS`)
+	}
+	// Changing the copy leaves the registry alone.
+	delete(Palettes(), "basic")
+	if _, ok := Palettes()["basic"]; !ok {
+		t.Error("deleting from Palettes() removed a palette")
+	}
+	bricks := generateBricks(basicShapes, BRIGHT_RED)
+	RegisterPalette("test", bricks)
+	defer func() {
+		palettesMu.Lock()
+		delete(palettes, "test")
+		palettesMu.Unlock()
+	}()
+	if got := Palettes()["test"]; len(got) != len(bricks) || got[0] != bricks[0] {
+		t.Errorf("registered palette is %v, want %v", got, bricks)
+	}
+}
+
+func TestColorLookups(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		want Color
+		ok   bool
+	}{
+		{21, BRIGHT_RED, true},
+		{199, DARK_STONE_GREY, true},
+		{41, TRANS_RED, true},
+		{0, Color{}, false},
+		{-1, Color{}, false},
+	} {
+		if got, ok := ColorByID(tc.id); got != tc.want || ok != tc.ok {
+			t.Errorf("ColorByID(%d) = %v, %v, want %v, %v", tc.id, got.name, ok, tc.want.name, tc.ok)
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		want Color
+		ok   bool
+	}{
+		{"Bright red", BRIGHT_RED, true},
+		{"bright RED (#21)", BRIGHT_RED, true},
+		{" White ", WHITE, true},
+		{"Dark stone grey (#199)", DARK_STONE_GREY, true},
+		{"Tr. red", TRANS_RED, true},
+		{"Bright red (#1)", Color{}, false},
+		{"red", Color{}, false},
+		{"", Color{}, false},
+	} {
+		if got, ok := ColorByName(tc.name); got != tc.want || ok != tc.ok {
+			t.Errorf("ColorByName(%q) = %v, %v, want %v, %v", tc.name, got.name, ok, tc.want.name, tc.ok)
+		}
+	}
+}
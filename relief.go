@@ -0,0 +1,211 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ReliefPanel is a bas-relief made of stacked plates: every stud is a column
+// of plates in the color of the image, higher where the image is brighter.
+// Each layer is laid like a panel whose bricks stand for plates of the same
+// size, crossing the layer below for strength.
+type ReliefPanel struct {
+	layers []*Panel
+	height map[image.Point]int
+	bounds image.Rectangle
+}
+
+// NewReliefPanel builds a relief of img, from one plate high where the image
+// is darkest to levels plates where it is brightest. Options are used as in
+// NewPanel, except for Overlays and Frame which are ignored. MaxInventory
+// is shared by all layers.
+func NewReliefPanel(img image.Image, opt *Options, levels int) (*ReliefPanel, error) {
+	if levels < 1 {
+		return nil, errors.New("lego: a relief needs at least one level")
+	}
+	o := *opt
+	o.Overlays, o.Frame = nil, FrameSpec{}
+	flat, err := NewPanel(img, &o)
+	if err != nil {
+		return nil, err
+	}
+	src, err := target(img, &o)
+	if err != nil {
+		return nil, err
+	}
+	// The layers copy colors from flat, which already has the substitutes.
+	o.Bricks, o.Substitutions = substituteBricks(o.Bricks, o.Substitutions), nil
+	r := &ReliefPanel{height: make(map[image.Point]int), bounds: flat.bounds}
+	colors := make(map[image.Point]Color)
+	flat.cells().each(func(pt, pos image.Point) {
+		colors[pt] = flat.bricks.at(pos).Color
+		b := brightness(src.At(pt.X, pt.Y))
+		r.height[pt] = 1 + int(math.Floor(b*float64(levels-1)+0.5))
+	})
+	used := make(Inventory)
+	for level := 0; level < levels; level++ {
+		layer := make(map[image.Point]Color)
+		for pt, c := range colors {
+			if r.height[pt] > level {
+				layer[pt] = c
+			}
+		}
+		if len(layer) == 0 {
+			break
+		}
+		p, err := placeLayer(r.bounds, layer, &o, level%2 == 1, used)
+		if err != nil {
+			return nil, fmt.Errorf("lego: layer %d: %s", level+1, strings.TrimPrefix(err.Error(), "lego: "))
+		}
+		for brick, n := range p.CountBricks() {
+			used[brick] += n
+		}
+		r.layers = append(r.layers, p)
+	}
+	return r, nil
+}
+
+// placeLayer covers the studs in colors with bricks, leaving the rest of
+// bounds empty. If across is set, bricks are laid column by column, so they
+// cross those of a layer laid row by row. Bricks in used are taken off
+// opt.MaxInventory.
+func placeLayer(bounds image.Rectangle, colors map[image.Point]Color, opt *Options, across bool, used Inventory) (*Panel, error) {
+	flip := func(pt image.Point) image.Point {
+		if across {
+			return image.Point{pt.Y, pt.X}
+		}
+		return pt
+	}
+	palette := color.Palette{color.Transparent}
+	m := make(map[color.Color]Color)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := colors[image.Point{x, y}]
+			if _, seen := m[c.color]; ok && !seen {
+				m[c.color] = c
+				palette = append(palette, c.color)
+			}
+		}
+	}
+	dst := image.NewPaletted(image.Rectangle{image.ZP, flip(bounds.Size())}, palette)
+	for pt, c := range colors {
+		q := flip(pt.Sub(bounds.Min))
+		dst.SetColorIndex(q.X, q.Y, uint8(palette.Index(c.color)))
+	}
+	p := newPanel(dst.Bounds())
+	helper := newHelper(opt.Bricks, dst, p, opt)
+	if helper.inventory != nil {
+		for brick, n := range used {
+			helper.inventory[brick] -= n
+		}
+	}
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if dst.ColorIndexAt(x, y) == 0 {
+				helper.visited.add(image.Point{x, y})
+			}
+		}
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if err := helper.placeBrick(image.Point{x, y}, m[dst.At(x, y)]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result := newPanel(bounds)
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		brick.Size = flip(brick.Size)
+		result.bricks.set(flip(pos).Add(bounds.Min), brick)
+	})
+	return result, nil
+}
+
+func (r *ReliefPanel) Size() image.Point {
+	return r.bounds.Size()
+}
+
+// Height returns the number of plates stacked on the stud at pt.
+func (r *ReliefPanel) Height(pt image.Point) int {
+	return r.height[pt]
+}
+
+// Layers returns the layers of the relief, from the bottom up. Their bricks
+// stand for plates of the same size and color.
+func (r *ReliefPanel) Layers() []*Panel {
+	return append([]*Panel(nil), r.layers...)
+}
+
+// CountPlates returns how many plates of each size and color the relief
+// needs, in canonical orientation.
+func (r *ReliefPanel) CountPlates() map[Brick]int {
+	result := make(map[Brick]int)
+	for _, layer := range r.layers {
+		for brick, n := range layer.CountBricks() {
+			result[brick] += n
+		}
+	}
+	return result
+}
+
+// ExportLDraw writes the relief as an LDraw model, one building step per
+// layer.
+func (r *ReliefPanel) ExportLDraw(w io.Writer, name string) error {
+	out := &errWriter{w: w}
+	ldrawHeader(out, name)
+	for level, layer := range r.layers {
+		for _, pos := range layer.positions() {
+			brick := *layer.bricks.at(pos)
+			part, ok := plateParts[brick.canonical().Size]
+			if !ok {
+				return fmt.Errorf("lego: no plate for %v", brick)
+			}
+			ldrawPart(out, part.number, brick, pos.Sub(r.bounds.Min), -ldrawPlate*(level+1))
+		}
+		ldrawStep(out)
+	}
+	return out.err
+}
+
+// WriteInstructions writes, for every layer from the bottom up, the plates
+// it needs and where each goes, by column and row of its top-left stud.
+func (r *ReliefPanel) WriteInstructions(w io.Writer) error {
+	return writeLayers(w, r.layers, r.bounds, "plate")
+}
+
+// writeLayers writes building instructions for layers, calling their parts
+// noun.
+func writeLayers(w io.Writer, layers []*Panel, bounds image.Rectangle, noun string) error {
+	out := &errWriter{w: w}
+	for level, layer := range layers {
+		count := layer.CountBricks()
+		var parts []Brick
+		for brick := range count {
+			parts = append(parts, brick)
+		}
+		sortBricks(parts)
+		out.printf("Layer %d\n", level+1)
+		for _, brick := range parts {
+			out.printf("  %d x %s %s %s\n", count[brick], brick.sizeName(), noun, brick.Color.name)
+		}
+		positions := layer.positions()
+		sort.SliceStable(positions, func(i, j int) bool {
+			return layer.bricks.at(positions[i]).Color.name < layer.bricks.at(positions[j]).Color.name
+		})
+		for _, pos := range positions {
+			brick := layer.bricks.at(pos)
+			rel := pos.Sub(bounds.Min)
+			out.printf("  %s %s %s at %d,%d\n", brick.sizeName(), noun, brick.Color.name, rel.X+1, rel.Y+1)
+		}
+		out.printf("\n")
+	}
+	return out.err
+}
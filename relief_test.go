@@ -0,0 +1,74 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+// columns returns an image one pixel per stud, h high, with a column of
+// width studs in each of colors, from left to right.
+func columns(width, h int, colors ...Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width*len(colors), h))
+	for i, c := range colors {
+		draw.Draw(img, image.Rect(i*width, 0, (i+1)*width, h), &image.Uniform{c.color}, image.ZP, draw.Src)
+	}
+	return img
+}
+
+func TestNewReliefPanel(t *testing.T) {
+	img := columns(4, 4, BLACK, MEDIUM_STONE_GREY, WHITE)
+	r, err := NewReliefPanel(img, &Options{Width: 12, Bricks: ALL_BRICKS}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != (image.Point{12, 4}) {
+		t.Fatalf("size %v", r.Size())
+	}
+	for x, want := range []int{1, 2, 3} {
+		if h := r.Height(image.Point{4*x + 1, 2}); h != want {
+			t.Errorf("column %d is %d plates high, want %d", x, h, want)
+		}
+	}
+	layers := r.Layers()
+	if len(layers) != 3 {
+		t.Fatalf("%d layers", len(layers))
+	}
+	total := make(map[Brick]int)
+	for i, layer := range layers {
+		// Each layer covers the columns at least that high, in the color
+		// of the image.
+		colors := studColors(layer)
+		if want := 4 * 4 * (3 - i); len(colors) != want {
+			t.Errorf("layer %d covers %d studs, want %d", i, len(colors), want)
+		}
+		for pt, c := range colors {
+			if r.Height(pt) <= i || c.color != img.At(pt.X, pt.Y) {
+				t.Errorf("layer %d has %v at %v", i, c, pt)
+			}
+		}
+		// Bricks on every other layer are turned, to cross the ones
+		// below.
+		layer.ForEach(func(pos image.Point, b Brick) {
+			if i%2 == 0 && b.Size.Y < b.Size.X || i%2 == 1 && b.Size.X < b.Size.Y {
+				t.Errorf("layer %d has %v at %v", i, b.Size, pos)
+			}
+		})
+		for brick, n := range layer.CountBricks() {
+			total[brick] += n
+		}
+	}
+	plates := r.CountPlates()
+	if len(plates) != len(total) {
+		t.Errorf("%d kinds of plates, want %d", len(plates), len(total))
+	}
+	for brick, n := range total {
+		if plates[brick] != n {
+			t.Errorf("%d plates %v, want %d", plates[brick], brick, n)
+		}
+	}
+	if _, err := NewReliefPanel(img, &Options{Width: 12, Bricks: ALL_BRICKS}, 0); err == nil {
+		t.Error("built a relief without levels")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+
+// Package render draws life-like previews of Lego panels: bricks get
+// beveled edges, studs get specular highlights and soft shadows, and the
+// plastic gets a slight texture.
+//
+// Importing this package also makes lego.Realistic available to
+// Panel.DrawWith:
+//
+//	import _ "github.com/mrbubble/lego/render"
+package render
+
+import (
+	"github.com/mrbubble/lego"
+	"image"
+	"image/color"
+	"math"
+)
+
+func init() {
+	lego.RegisterStyle(lego.Realistic, func(p *lego.Panel, opt *lego.DrawOptions) image.Image {
+		return Draw(p, opt.Scale, opt.Outline)
+	})
+}
+
+func smoothstep(edge0, edge1, x float64) float64 {
+	t := (x - edge0) / (edge1 - edge0)
+	if t < 0 {
+		return 0
+	}
+	if t > 1 {
+		return 1
+	}
+	return t * t * (3 - 2*t)
+}
+
+// noise returns a repeatable pseudo-random value in [-1, 1] for a pixel.
+func noise(x, y int) float64 {
+	h := uint32(x)*374761393 + uint32(y)*668265263
+	h = (h ^ (h >> 13)) * 1274126177
+	h ^= h >> 16
+	return float64(h&0xffff)/32767.5 - 1
+}
+
+func clamp(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// light returns how much a pixel of a brick is lit, given its position
+// relative to the brick's area r and the stud of its cell.
+func light(x, y int, r image.Rectangle, scale int, seam float64) (shade, specular float64) {
+	fx, fy := float64(x)+0.5, float64(y)+0.5
+	shade = 1
+
+	// Bevels: light on the top and left edges, dark on the others, and a
+	// seam between bricks.
+	bevel := float64(scale) / 8
+	if bevel < 1 {
+		bevel = 1
+	}
+	near := math.Min(fx-float64(r.Min.X), fy-float64(r.Min.Y))
+	far := math.Min(float64(r.Max.X)-fx, float64(r.Max.Y)-fy)
+	switch {
+	case math.Min(near, far) < 1:
+		shade = seam
+	case near < bevel && near <= far:
+		shade = 1.12
+	case far < bevel:
+		shade = 0.8
+	}
+
+	// Studs, lit from the top left, cast a soft shadow to the bottom right.
+	s := float64(scale)
+	cx := math.Floor(fx/s)*s + s/2
+	cy := math.Floor(fy/s)*s + s/2
+	radius := 0.3 * s
+	d := math.Hypot(fx-cx, fy-cy)
+	if d > radius {
+		ds := math.Hypot(fx-cx-0.15*s, fy-cy-0.15*s)
+		shade *= 0.75 + 0.25*smoothstep(radius*0.8, radius*1.2, ds)
+		return shade, 0
+	}
+	shade *= 1.04
+	if d > radius-math.Max(1, s/32) {
+		shade *= 0.85
+	}
+	dh := math.Hypot(fx-cx+0.35*radius, fy-cy+0.35*radius)
+	if spec := 1 - dh/(0.5*radius); spec > 0 {
+		specular = 0.55 * spec * spec
+	}
+	return shade, specular
+}
+
+// Draw renders the panel with the given number of pixels per stud. If
+// outline is set, the seams between bricks are darker.
+func Draw(p *lego.Panel, scale int, outline bool) *image.NRGBA {
+	out := image.NewNRGBA(image.Rectangle{image.ZP, p.Size().Mul(scale)})
+	seam := 0.7
+	if outline {
+		seam = 0.4
+	}
+	p.ForEach(func(pos image.Point, b lego.Brick) {
+		base := color.NRGBAModel.Convert(b.Color.Color()).(color.NRGBA)
+		// pos is the first stud the brick covers.
+		for x := 0; x < b.Size.X && !b.Covers(image.Point{x, 0}); x++ {
+			pos.X--
+		}
+		min := pos.Mul(scale)
+		r := image.Rectangle{min, min.Add(b.Size.Mul(scale))}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				stud := image.Point{(x - min.X) / scale, (y - min.Y) / scale}
+				if !b.Covers(stud) {
+					continue
+				}
+				cell := r
+				if b.Mask != 0 {
+					// Shaped bricks are lit stud by stud.
+					cell = image.Rectangle{min.Add(stud.Mul(scale)), min.Add(stud.Add(image.Point{1, 1}).Mul(scale))}
+				}
+				shade, specular := light(x, y, cell, scale, seam)
+				shade *= 1 + 0.03*noise(x, y)
+				spec := 255 * specular
+				out.SetNRGBA(x, y, color.NRGBA{
+					clamp(float64(base.R)*shade*(1-specular) + spec),
+					clamp(float64(base.G)*shade*(1-specular) + spec),
+					clamp(float64(base.B)*shade*(1-specular) + spec),
+					255,
+				})
+			}
+		}
+	})
+	for pt, c := range p.Overlay() {
+		drawPlate(out, pt, c.Color(), scale)
+	}
+	return out
+}
+
+// drawPlate draws a translucent round plate over a stud, with a rim and a
+// highlight like the studs.
+func drawPlate(out *image.NRGBA, pt image.Point, c color.Color, scale int) {
+	tint := color.NRGBAModel.Convert(c).(color.NRGBA)
+	alpha := float64(tint.A) / 255
+	s := float64(scale)
+	cx, cy := (float64(pt.X)+0.5)*s, (float64(pt.Y)+0.5)*s
+	radius := 0.45 * s
+	for y := pt.Y * scale; y < (pt.Y+1)*scale; y++ {
+		for x := pt.X * scale; x < (pt.X+1)*scale; x++ {
+			fx, fy := float64(x)+0.5, float64(y)+0.5
+			d := math.Hypot(fx-cx, fy-cy)
+			if d > radius {
+				continue
+			}
+			a := alpha
+			if d > radius-math.Max(1, s/24) {
+				a = math.Min(1, a*1.5)
+			}
+			spec := 0.0
+			if h := 1 - math.Hypot(fx-cx+0.4*radius, fy-cy+0.4*radius)/(0.4*radius); h > 0 {
+				spec = 0.5 * h * h
+			}
+			under := out.NRGBAAt(x, y)
+			mix := func(u, t uint8) uint8 {
+				return clamp((float64(u)*(1-a)+float64(t)*a)*(1-spec) + 255*spec)
+			}
+			out.SetNRGBA(x, y, color.NRGBA{mix(under.R, tint.R), mix(under.G, tint.G), mix(under.B, tint.B), 255})
+		}
+	}
+}
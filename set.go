@@ -0,0 +1,111 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+)
+
+// BuildSet builds one panel per image with the same options, such as the
+// parts of a triptych. If MaxInventory is set, it is shared by all panels:
+// scarce bricks are split in proportion to what each panel would use
+// without limits, and whatever a panel leaves unused goes to the next ones.
+func BuildSet(imgs []image.Image, opt *Options) ([]*Panel, error) {
+	panels := make([]*Panel, len(imgs))
+	if opt.MaxInventory == nil {
+		for i, img := range imgs {
+			p, err := NewPanel(img, opt)
+			if err != nil {
+				return nil, setError(i, err)
+			}
+			panels[i] = p
+		}
+		return panels, nil
+	}
+
+	// Find what each panel would use with unlimited bricks.
+	free := *opt
+	free.MaxInventory = nil
+	demand := make([]map[Brick]int, len(imgs))
+	for i, img := range imgs {
+		p, err := NewPanel(img, &free)
+		if err != nil {
+			return nil, setError(i, err)
+		}
+		demand[i] = p.CountBricks()
+	}
+	shares := allocate(opt.MaxInventory.clone(), demand)
+
+	// Build each panel with its share, plus anything not reserved for the
+	// panels after it.
+	left := opt.MaxInventory.clone()
+	reserved := make(Inventory)
+	for _, share := range shares {
+		for brick, n := range share {
+			reserved[brick] += n
+		}
+	}
+	for i, img := range imgs {
+		for brick, n := range shares[i] {
+			reserved[brick] -= n
+		}
+		limited := *opt
+		limited.MaxInventory = make(Inventory)
+		for brick, n := range left {
+			limited.MaxInventory[brick] = n - reserved[brick]
+		}
+		p, err := NewPanel(img, &limited)
+		if err != nil {
+			return nil, setError(i, err)
+		}
+		for brick, n := range p.CountBricks() {
+			left[brick] -= n
+		}
+		panels[i] = p
+	}
+	return panels, nil
+}
+
+// allocate splits inv among the demands, in proportion to them when there
+// isn't enough for all. Remainders go to the largest fractions.
+func allocate(inv Inventory, demand []map[Brick]int) []Inventory {
+	shares := make([]Inventory, len(demand))
+	for i := range shares {
+		shares[i] = make(Inventory)
+	}
+	for brick, have := range inv {
+		total := 0
+		for _, d := range demand {
+			total += d[brick]
+		}
+		if total == 0 {
+			continue
+		}
+		if total <= have {
+			for i, d := range demand {
+				shares[i][brick] = d[brick]
+			}
+			continue
+		}
+		type part struct{ i, rest int }
+		var parts []part
+		given := 0
+		for i, d := range demand {
+			n := have * d[brick] / total
+			shares[i][brick] = n
+			given += n
+			parts = append(parts, part{i, have * d[brick] % total})
+		}
+		sort.SliceStable(parts, func(a, b int) bool { return parts[a].rest > parts[b].rest })
+		for _, part := range parts[:have-given] {
+			shares[part.i][brick]++
+		}
+	}
+	return shares
+}
+
+func setError(i int, err error) error {
+	return fmt.Errorf("lego: image %d: %s", i, strings.TrimPrefix(err.Error(), "lego: "))
+}
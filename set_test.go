@@ -0,0 +1,80 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	one := Brick{Size: image.Point{1, 1}, Color: WHITE}
+	two := Brick{Size: image.Point{1, 2}, Color: WHITE}
+	for _, tc := range []struct {
+		name   string
+		inv    Inventory
+		demand []map[Brick]int
+		want   []Inventory
+	}{
+		{"enough", Inventory{one: 10}, []map[Brick]int{{one: 3}, {one: 4}},
+			[]Inventory{{one: 3}, {one: 4}}},
+		{"proportional", Inventory{one: 6}, []map[Brick]int{{one: 4}, {one: 8}},
+			[]Inventory{{one: 2}, {one: 4}}},
+		{"remainders to the largest fractions", Inventory{one: 5}, []map[Brick]int{{one: 3}, {one: 3}, {one: 1}},
+			[]Inventory{{one: 2}, {one: 2}, {one: 1}}},
+		{"none left", Inventory{one: 0}, []map[Brick]int{{one: 3}, {one: 1}},
+			[]Inventory{{one: 0}, {one: 0}}},
+		{"not wanted", Inventory{one: 4, two: 4}, []map[Brick]int{{one: 1}, {}},
+			[]Inventory{{one: 1}, {one: 0}}},
+	} {
+		if got := allocate(tc.inv, tc.demand); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBuildSet(t *testing.T) {
+	imgs := []image.Image{gradient(40, 20), gradient(20, 40), gradient(30, 30)}
+	opt := &Options{Width: 24, Bricks: BASIC_BRICKS}
+	inv := make(Inventory)
+	for _, img := range imgs {
+		p, err := NewPanel(img, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for b, n := range p.CountBricks() {
+			inv[b] += n
+		}
+	}
+	// Take away half of the 2x4s, and add 1x1s to make up for them, so
+	// that the panels have to share the ones left.
+	for b, n := range inv {
+		if b.Size == (image.Point{2, 4}) && n > 2 {
+			inv[b] = n / 2
+			inv[Brick{Size: image.Point{1, 1}, Color: b.Color}] += 8 * (n - n/2)
+		}
+	}
+	opt.MaxInventory = inv
+	panels, err := BuildSet(imgs, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(Inventory)
+	for i, p := range panels {
+		if len(p.CountBricks()) == 0 {
+			t.Errorf("panel %d is empty", i)
+		}
+		for b, n := range p.CountBricks() {
+			used[b] += n
+		}
+	}
+	for b, n := range used {
+		if n > inv[b] {
+			t.Errorf("%v: used %d of %d", b, n, inv[b])
+		}
+	}
+	short := Inventory{Brick{Size: image.Point{1, 1}, Color: WHITE}: 1}
+	if _, err := BuildSet(imgs, &Options{Width: 24, Bricks: BASIC_BRICKS, MaxInventory: short}); err == nil {
+		t.Error("built a set with too few bricks")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// CORNER is the Mask of a 2x2 corner brick, covering all studs but the
+// bottom-right one.
+const CORNER uint64 = 0x7
+
+// Covers reports whether the brick covers the stud at pt, relative to its
+// top-left corner.
+func (b Brick) Covers(pt image.Point) bool {
+	if !pt.In(image.Rectangle{image.ZP, b.Size}) {
+		return false
+	}
+	return b.Mask == 0 || b.Mask&(1<<uint(pt.Y*b.Size.X+pt.X)) != 0
+}
+
+// Area returns the number of studs the brick covers.
+func (b Brick) Area() int {
+	if b.Mask == 0 {
+		return b.Size.X * b.Size.Y
+	}
+	return bits.OnesCount64(b.Mask)
+}
+
+// anchor returns the top-left stud of the brick, the first one it covers in
+// row-major order, relative to the corner of its Size. The position of a
+// brick in a panel is that of this stud.
+func (b Brick) anchor() image.Point {
+	for y := 0; y < b.Size.Y; y++ {
+		for x := 0; x < b.Size.X; x++ {
+			if b.Covers(image.Point{x, y}) {
+				return image.Point{x, y}
+			}
+		}
+	}
+	return image.ZP
+}
+
+// rotate turns the brick a quarter clockwise.
+func (b Brick) rotate() Brick {
+	result := Brick{image.Point{b.Size.Y, b.Size.X}, b.Color, 0, b.Technic}
+	if b.Mask == 0 {
+		return result
+	}
+	for y := 0; y < b.Size.Y; y++ {
+		for x := 0; x < b.Size.X; x++ {
+			if b.Covers(image.Point{x, y}) {
+				result.Mask |= 1 << uint(x*result.Size.X+b.Size.Y-1-y)
+			}
+		}
+	}
+	return result
+}
+
+// mirror flips the brick left to right.
+func (b Brick) mirror() Brick {
+	result := Brick{b.Size, b.Color, 0, b.Technic}
+	if b.Mask == 0 {
+		return result
+	}
+	for y := 0; y < b.Size.Y; y++ {
+		for x := 0; x < b.Size.X; x++ {
+			if b.Covers(image.Point{x, y}) {
+				result.Mask |= 1 << uint(y*b.Size.X+b.Size.X-1-x)
+			}
+		}
+	}
+	return result
+}
+
+// overlaps reports whether bricks a and b, at positions pa and pb, cover a
+// stud in common.
+func overlaps(a Brick, pa image.Point, b Brick, pb image.Point) bool {
+	pa, pb = pa.Sub(a.anchor()), pb.Sub(b.anchor())
+	r := image.Rectangle{pa, pa.Add(a.Size)}.Intersect(image.Rectangle{pb, pb.Add(b.Size)})
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			pt := image.Point{x, y}
+			if a.Covers(pt.Sub(pa)) && b.Covers(pt.Sub(pb)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sizeName describes the shape of the brick for parts lists, such as "2x4"
+// or "2x2 corner".
+func (b Brick) sizeName() string {
+	name := fmt.Sprintf("%dx%d", b.Size.X, b.Size.Y)
+	if c := b.canonical(); c.Mask == CORNER && c.Size == (image.Point{2, 2}) {
+		name += " corner"
+	} else if b.Mask != 0 {
+		name += " shaped"
+	}
+	if b.Technic {
+		name += " Technic"
+	}
+	return name
+}
@@ -0,0 +1,102 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	xdraw "golang.org/x/image/draw"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// SpriteAtlas holds pictures of bricks, such as photos or renders of white
+// or gray bricks seen from above, by size: the sprite for {X, Y} is X studs
+// wide and Y studs high, and is turned a quarter for bricks lying the other
+// way if there is no sprite for them. Sprites are stretched over the brick
+// and tinted to its color, keeping their transparency, so their average
+// brightness comes out as the brick color and their shading is kept.
+type SpriteAtlas map[image.Point]image.Image
+
+// sprites draws bricks from an atlas, remembering every brick it tinted.
+type sprites struct {
+	atlas SpriteAtlas
+	scale int
+	done  map[Brick]*image.NRGBA
+}
+
+// draw draws brick at pos and reports whether there is a sprite for it.
+// Shaped bricks never have one.
+func (s *sprites) draw(out draw.Image, pos image.Point, brick *Brick) bool {
+	if len(s.atlas) == 0 || brick.Mask != 0 {
+		return false
+	}
+	key := Brick{brick.Size, brick.Color, 0, false}
+	img, ok := s.done[key]
+	if !ok {
+		sprite, turned := s.atlas[brick.Size], false
+		if sprite == nil {
+			sprite, turned = s.atlas[image.Point{brick.Size.Y, brick.Size.X}], true
+		}
+		if sprite == nil {
+			return false
+		}
+		if turned {
+			sprite = rotateImage(sprite)
+		}
+		r := image.Rectangle{image.ZP, brick.Size.Mul(s.scale)}
+		scaled := image.NewNRGBA(r)
+		xdraw.CatmullRom.Scale(scaled, r, sprite, sprite.Bounds(), draw.Src, nil)
+		img = tint(scaled, brick.Color.color)
+		if s.done == nil {
+			s.done = make(map[Brick]*image.NRGBA)
+		}
+		s.done[key] = img
+	}
+	min := pos.Sub(brick.anchor()).Mul(s.scale)
+	draw.Draw(out, img.Bounds().Add(min), img, image.ZP, draw.Over)
+	return true
+}
+
+// rotateImage returns img turned a quarter clockwise.
+func rotateImage(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(b.Max.Y-1-y, x-b.Min.X, img.At(x, y))
+		}
+	}
+	return out
+}
+
+// tint recolors img in place so its average brightness, weighted by alpha,
+// becomes c, and returns it.
+func tint(img *image.NRGBA, c color.Color) *image.NRGBA {
+	luma := func(p []uint8) float64 {
+		return (299*float64(p[0]) + 587*float64(p[1]) + 114*float64(p[2])) / 1000
+	}
+	sum, weight := 0.0, 0.0
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := float64(img.Pix[i+3])
+		sum += luma(img.Pix[i:]) * a
+		weight += a
+	}
+	mean := 0.0
+	if weight > 0 {
+		mean = sum / weight
+	}
+	base := color.NRGBAModel.Convert(c).(color.NRGBA)
+	clamp := func(v float64) uint8 {
+		if v > 255 {
+			return 255
+		}
+		return uint8(v + 0.5)
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		f := 1.0
+		if mean > 0 {
+			f = luma(img.Pix[i:]) / mean
+		}
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = clamp(float64(base.R)*f), clamp(float64(base.G)*f), clamp(float64(base.B)*f)
+	}
+	return img
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSprites(t *testing.T) {
+	// The sprite of a 1x2 brick: dark on top, light at the bottom, averaging
+	// to mid gray.
+	sprite := image.NewGray(image.Rect(0, 0, 4, 8))
+	for i := range sprite.Pix {
+		sprite.Pix[i] = 64
+		if i >= len(sprite.Pix)/2 {
+			sprite.Pix[i] = 192
+		}
+	}
+	p := newPanel(image.Rect(0, 0, 3, 2))
+	for _, b := range []struct {
+		pos   image.Point
+		brick Brick
+	}{
+		{image.Point{0, 0}, Brick{image.Point{1, 2}, BRIGHT_BLUE, 0, false}},
+		{image.Point{1, 0}, Brick{image.Point{2, 1}, BRIGHT_BLUE, 0, false}},
+		{image.Point{1, 1}, Brick{image.Point{1, 1}, BRIGHT_BLUE, 0, false}},
+	} {
+		if err := p.Place(b.pos, b.brick); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const scale = 8
+	// green returns the green channel at the middle of the stud at (x, y).
+	green := func(img image.Image, x, y int) int {
+		c := color.NRGBAModel.Convert(img.At(x*scale+scale/2, y*scale+scale/2)).(color.NRGBA)
+		return int(c.G)
+	}
+	blue := int(BRIGHT_BLUE.color.(color.NRGBA).G)
+	for _, tc := range []struct {
+		name    string
+		sprites SpriteAtlas
+		// Shading of the studs, in percent of the brick color.
+		want [2][3]int
+	}{
+		{"no sprites", nil, [2][3]int{{100, 100, 100}, {100, 100, 0}}},
+		// The horizontal brick gets the sprite turned clockwise, so its
+		// dark top ends up on the right.
+		{"sprites", SpriteAtlas{{1, 2}: sprite}, [2][3]int{{50, 150, 50}, {150, 100, 0}}},
+	} {
+		img := p.DrawWith(&DrawOptions{Scale: scale, Sprites: tc.sprites})
+		for y, row := range tc.want {
+			for x, want := range row {
+				want = want * blue / 100
+				if got := green(img, x, y); got < want-4 || got > want+4 {
+					t.Errorf("%s: green at stud (%d, %d) is %d, want %d", tc.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// Stats summarizes the bricks of a panel.
+type Stats struct {
+	Bricks     int           // Number of bricks.
+	Studs      int           // Number of studs covered.
+	ColorStuds map[Color]int // Studs covered by each color.
+	// Largest and Smallest are the sizes of the largest and smallest bricks
+	// by area, and LargestShare and SmallestShare the fractions of the
+	// studs that bricks of those areas cover.
+	Largest, Smallest           image.Point
+	LargestShare, SmallestShare float64
+}
+
+// Stats returns totals about the bricks in the panel.
+func (p *Panel) Stats() Stats {
+	s := Stats{ColorStuds: make(map[Color]int)}
+	areas := make(map[int]int)
+	// Shaped bricks cover fewer studs than their size, so keep their areas.
+	largest, smallest := 0, 0
+	p.bricks.each(func(_ image.Point, brick *Brick) {
+		area := brick.Area()
+		s.Bricks++
+		s.Studs += area
+		s.ColorStuds[brick.Color] += area
+		areas[area] += area
+		size := brick.canonical().Size
+		if s.Bricks == 1 || area > largest {
+			s.Largest, largest = size, area
+		}
+		if s.Bricks == 1 || area < smallest {
+			s.Smallest, smallest = size, area
+		}
+	})
+	if s.Studs > 0 {
+		s.LargestShare = float64(areas[largest]) / float64(s.Studs)
+		s.SmallestShare = float64(areas[smallest]) / float64(s.Studs)
+	}
+	return s
+}
+
+// DrawHistogram renders a bar chart of the studs each color covers, most
+// used first, with bars scale pixels thick, for instruction booklets.
+func (s *Stats) DrawHistogram(scale int) image.Image {
+	var colors []Color
+	for c := range s.ColorStuds {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i], colors[j]
+		if s.ColorStuds[a] != s.ColorStuds[b] {
+			return s.ColorStuds[a] > s.ColorStuds[b]
+		}
+		return a.name < b.name
+	})
+
+	height, gap := minInt(scale, labelFace.Height), scale/4
+	label := func(c Color) string {
+		return fmt.Sprintf("%s: %d", c.name, s.ColorStuds[c])
+	}
+	names := 0
+	for _, c := range colors {
+		if w := textWidth(label(c), height); w > names {
+			names = w
+		}
+	}
+	bars := 20 * scale
+	out := image.NewNRGBA(image.Rect(0, 0, gap+names+gap+bars+gap, gap+len(colors)*(scale+gap)))
+	draw.Draw(out, out.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
+	most := 1
+	if len(colors) > 0 {
+		most = s.ColorStuds[colors[0]]
+	}
+	for i, c := range colors {
+		top := gap + i*(scale+gap)
+		text := label(c)
+		drawText(out, text, image.Point{gap + names - textWidth(text, height), top + scale/2}, height, color.Black)
+		length := bars * s.ColorStuds[c] / most
+		if length < 1 {
+			length = 1
+		}
+		bar := image.Rect(0, 0, length, scale).Add(image.Point{gap + names + gap, top})
+		draw.Draw(out, bar, &image.Uniform{color.Black}, image.ZP, draw.Src)
+		draw.Draw(out, bar.Inset(1), &image.Uniform{c.color}, image.ZP, draw.Src)
+	}
+	return out
+}
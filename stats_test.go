@@ -0,0 +1,51 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	corner := Brick{image.Point{2, 2}, WHITE, CORNER, false}
+	type placed struct {
+		pos   image.Point
+		brick Brick
+	}
+	for _, tc := range []struct {
+		name                        string
+		bricks                      []placed
+		largest, smallest           image.Point
+		largestShare, smallestShare float64
+	}{
+		{"empty", nil, image.ZP, image.ZP, 0, 0},
+		{"plain", []placed{
+			{image.Point{0, 0}, Brick{image.Point{4, 1}, WHITE, 0, false}},
+			{image.Point{0, 1}, Brick{image.Point{1, 1}, BLACK, 0, false}},
+			{image.Point{1, 1}, Brick{image.Point{2, 1}, BLACK, 0, false}},
+		}, image.Point{1, 4}, image.Point{1, 1}, 4.0 / 7, 1.0 / 7},
+		{"corner before a larger brick", []placed{
+			{image.Point{0, 0}.Add(corner.anchor()), corner},
+			{image.Point{0, 2}, Brick{image.Point{4, 1}, WHITE, 0, false}},
+		}, image.Point{1, 4}, image.Point{2, 2}, 4.0 / 7, 3.0 / 7},
+		{"corner after a smaller brick", []placed{
+			{image.Point{0, 0}, Brick{image.Point{2, 1}, WHITE, 0, false}},
+			{image.Point{0, 1}.Add(corner.anchor()), corner},
+		}, image.Point{2, 2}, image.Point{1, 2}, 3.0 / 5, 2.0 / 5},
+	} {
+		// Sizes are those of the bricks upright, as in CountBricks.
+		p := newPanel(image.Rect(0, 0, 8, 8))
+		for _, b := range tc.bricks {
+			if err := p.Place(b.pos, b.brick); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		s := p.Stats()
+		if s.Largest != tc.largest || s.Smallest != tc.smallest {
+			t.Errorf("%s: largest %v and smallest %v, want %v and %v", tc.name, s.Largest, s.Smallest, tc.largest, tc.smallest)
+		}
+		if s.LargestShare != tc.largestShare || s.SmallestShare != tc.smallestShare {
+			t.Errorf("%s: shares %v and %v, want %v and %v", tc.name, s.LargestShare, s.SmallestShare, tc.largestShare, tc.smallestShare)
+		}
+	}
+}
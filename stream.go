@@ -0,0 +1,154 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nfnt/resize"
+	"image"
+	"io"
+)
+
+// STREAM_BAND is the height, in studs, of the segments NewPanelStream
+// builds, matching a 32x32 baseplate.
+var STREAM_BAND = 32
+
+// SegmentSink receives the panel built by NewPanelStream one band at a time,
+// from the top down. offset is the position of the segment's top-left stud
+// in the whole panel.
+type SegmentSink interface {
+	WriteSegment(offset image.Point, segment *Panel) error
+}
+
+// SegmentSinkFunc adapts a function to a SegmentSink.
+type SegmentSinkFunc func(offset image.Point, segment *Panel) error
+
+func (f SegmentSinkFunc) WriteSegment(offset image.Point, segment *Panel) error {
+	return f(offset, segment)
+}
+
+// NewPanelStream builds a panel from the image read from r, in any format
+// registered with package image, like NewPanel would, but hands it to sink in
+// bands of STREAM_BAND rows instead of keeping it all in memory. The image
+// is decoded whole, but resized, mapped to colors and covered with bricks
+// one band at a time, so MAX_PANEL_AREA only limits the size of a band and,
+// with Height left unset, murals can be as tall as the image allows.
+// Bricks don't cross from one band to the next, and the resizing,
+// Adjust.Sharpen and Dither don't carry across band edges either, so the
+// studs along them may differ from NewPanel's. Posterize picks its colors
+// once, from a preview of the whole image. Frame is not supported.
+func NewPanelStream(r io.Reader, opt *Options, sink SegmentSink) error {
+	if err := opt.Validate(); err != nil {
+		return err
+	}
+	if opt.Frame.Width != 0 {
+		return errors.New("lego: frames are not supported when streaming")
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+	if img.Bounds().Empty() {
+		return errors.New("lego: empty image")
+	}
+	src, err := sourceRect(img, opt)
+	if err != nil {
+		return err
+	}
+	g := newGeometry(src, opt)
+	o := *opt
+	o.MaxInventory = opt.MaxInventory.clone()
+	if opt.Style.Kind == Posterize {
+		if err := o.posterizeOnce(img, g); err != nil {
+			return err
+		}
+	}
+	cache := newBuildCache(&o)
+	band := STREAM_BAND
+	if band <= 0 {
+		band = 32
+	}
+	b := g.bounds
+	for i, y := 0, b.Min.Y; y < b.Max.Y; i, y = i+1, y+band {
+		r := image.Rect(b.Min.X, y, b.Max.X, y+band).Intersect(b)
+		if opt.Seed != 0 {
+			o.Seed = opt.Seed + int64(i)
+		}
+		bg := g.band(r)
+		if err := checkSize(bg.bounds.Size(), &o); err != nil {
+			return err
+		}
+		dst, m, overlay, err := (&resized{img.Bounds(), bg, bg.apply(img, &o)}).quantize(&o, cache)
+		if err != nil {
+			return err
+		}
+		segment, err := place(context.Background(), dst, m, &o, cache, nil)
+		if err != nil {
+			return err
+		}
+		segment.overlay = overlay
+		if o.MaxInventory != nil {
+			for brick, n := range segment.CountBricks() {
+				o.MaxInventory[brick] -= n
+			}
+		}
+		if err := sink.WriteSegment(r.Min.Sub(b.Min), segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// band returns the geometry of the rows of r, a band of g.bounds as wide as
+// it: the rows of the source image that cover them, resized to the rows of
+// r that aren't padding, and the padding of r.
+func (g geometry) band(r image.Rectangle) geometry {
+	ret := geometry{bounds: image.Rectangle{image.ZP, r.Size()}}
+	// The rows of the resized image in r, counted from its top.
+	top := clampInt(r.Min.Y-g.offset.Y, 0, g.size.Y)
+	bottom := clampInt(r.Max.Y-g.offset.Y, 0, g.size.Y)
+	if top == bottom {
+		return ret
+	}
+	row := func(y int) int {
+		return g.src.Min.Y + y*g.src.Dy()/g.size.Y
+	}
+	ret.src = image.Rect(g.src.Min.X, row(top), g.src.Max.X, row(bottom))
+	if ret.src.Dy() == 0 {
+		// Enlarged images have several rows of studs per row of pixels.
+		ret.src.Max.Y = ret.src.Min.Y + 1
+	}
+	ret.size = image.Point{g.size.X, bottom - top}
+	ret.offset = image.Point{g.offset.X, g.offset.Y + top - r.Min.Y}
+	return ret
+}
+
+// posterizeOnce makes opt map the image, band by band, to the colors
+// Posterize picks for the whole of it, which it finds from a preview of the
+// part of img that g resizes.
+func (opt *Options) posterizeOnce(img image.Image, g geometry) error {
+	if opt.Style.Levels < 1 {
+		return errors.New("lego: Posterize needs at least one level")
+	}
+	preview := resize.Thumbnail(256, 256, crop(img, g.src), opt.Interpolation.function())
+	chosen := make(map[Color]bool)
+	for _, brick := range ChoosePalette(opt.Adjust.adjust(preview), opt.Bricks, opt.Style.Levels) {
+		chosen[brick.Color] = true
+	}
+	var bricks []*Brick
+	for _, brick := range opt.Bricks {
+		if chosen[brick.Color] {
+			bricks = append(bricks, brick)
+		}
+	}
+	if opt.Lock != nil {
+		for _, c := range opt.Lock.Colors {
+			if !chosen[c] {
+				return fmt.Errorf("lego: locked color %s is not used by the style", c.name)
+			}
+		}
+	}
+	opt.Bricks, opt.Style = bricks, StyleSpec{}
+	return nil
+}
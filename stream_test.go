@@ -0,0 +1,92 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// streamColors streams img with opt, checks the bands are laid out one under
+// the other, and returns the color of every stud of the whole panel.
+func streamColors(t *testing.T, img image.Image, opt *Options) (map[image.Point]Color, image.Point) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	colors := make(map[image.Point]Color)
+	var size image.Point
+	err := NewPanelStream(&buf, opt, SegmentSinkFunc(func(offset image.Point, segment *Panel) error {
+		if offset != (image.Point{0, size.Y}) || segment.bounds.Min != image.ZP || segment.bounds.Dy() > STREAM_BAND {
+			t.Errorf("segment %v at %v", segment.bounds, offset)
+		}
+		if size.Y > 0 && segment.bounds.Dx() != size.X {
+			t.Errorf("segment %v is %d studs wide, not %d", segment.bounds, segment.bounds.Dx(), size.X)
+		}
+		size = image.Point{segment.bounds.Dx(), size.Y + segment.bounds.Dy()}
+		segment.cells().each(func(pt, pos image.Point) {
+			colors[pt.Add(offset)] = segment.bricks.at(pos).Color
+		})
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return colors, size
+}
+
+func TestNewPanelStream(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opt  Options
+		// exact is whether the studs all get the colors NewPanel gives
+		// them, as resizing the smooth test image band by band changes
+		// nothing, but dithering does.
+		exact bool
+	}{
+		{"photo", Options{Width: 80, Bricks: BASIC_BRICKS}, true},
+		{"LUT", Options{Width: 80, Bricks: ALL_BRICKS, LUTBits: 5}, true},
+		{"dither", Options{Width: 80, Bricks: BASIC_BRICKS, Dither: true}, false},
+		{"pad", Options{Width: 80, Height: 100, Fit: Pad, Bricks: BASIC_BRICKS}, true},
+		{"enlarged", Options{Width: 400, Bricks: BASIC_BRICKS}, true},
+		{"posterize", Options{Width: 80, Bricks: ALL_BRICKS, Style: StyleSpec{Kind: Posterize, Levels: 4}}, true},
+	} {
+		img := gradient(200, 150)
+		want, err := NewPanel(img, &tc.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, size := streamColors(t, img, &tc.opt)
+		if size != want.Size() {
+			t.Errorf("%s: streamed %v, want %v", tc.name, size, want.Size())
+		}
+		wantColors := studColors(want)
+		if len(got) != len(wantColors) {
+			t.Errorf("%s: %d studs, want %d", tc.name, len(got), len(wantColors))
+		}
+		if !tc.exact {
+			continue
+		}
+		for pt, c := range wantColors {
+			if got[pt] != c {
+				t.Errorf("%s: stud %v is %v, want %v", tc.name, pt, got[pt], c)
+				break
+			}
+		}
+	}
+}
+
+func TestNewPanelStreamArea(t *testing.T) {
+	defer func(area int) { MAX_PANEL_AREA = area }(MAX_PANEL_AREA)
+	MAX_PANEL_AREA = 40 * 40
+	img := gradient(20, 200)
+	opt := &Options{Width: 40, Bricks: BASIC_BRICKS}
+	if _, err := NewPanel(img, opt); err == nil {
+		t.Fatal("NewPanel built a panel over MAX_PANEL_AREA")
+	}
+	if _, size := streamColors(t, img, opt); size != (image.Point{40, 400}) {
+		t.Errorf("streamed %v, want 40x400", size)
+	}
+}
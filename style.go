@@ -0,0 +1,144 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// StyleKind selects an artistic treatment of the image in StyleSpec.
+type StyleKind int
+
+const (
+	// Photo reproduces the image as it is.
+	Photo StyleKind = iota
+	// Duotone maps the brightness of the image onto the shades between
+	// two colors, so only bricks of those two are used.
+	Duotone
+	// Posterize uses only the few palette colors that suit the image best,
+	// in flat bands.
+	Posterize
+	// Halftone draws dots of one color over another, larger where the
+	// image is darker, so brick sizes carry the shading.
+	Halftone
+)
+
+// HALFTONE_CELL is the distance between the centers of halftone dots, in
+// studs.
+var HALFTONE_CELL = 4
+
+// StyleSpec describes an artistic treatment of the image, applied while
+// mapping it to brick colors.
+type StyleSpec struct {
+	Kind StyleKind
+	// Dark and Light are the two colors of Duotone and Halftone, which
+	// must be among the bricks. Halftone dots are Dark.
+	Dark, Light Color
+	// Levels is the number of colors Posterize uses.
+	Levels int
+}
+
+// apply returns the palette and the image, one pixel per stud, to quantize
+// in the style.
+func (s StyleSpec) apply(palette color.Palette, src image.Image, opt *Options) (color.Palette, image.Image, error) {
+	var styled color.Palette
+	switch s.Kind {
+	case Photo:
+		return palette, src, nil
+	case Posterize:
+		if s.Levels < 1 {
+			return nil, nil, errors.New("lego: Posterize needs at least one level")
+		}
+		seen := make(map[color.Color]bool)
+		for _, brick := range ChoosePalette(src, opt.Bricks, s.Levels) {
+			if !seen[brick.Color.color] {
+				seen[brick.Color.color] = true
+				styled = append(styled, brick.Color.color)
+			}
+		}
+	case Duotone, Halftone:
+		for _, c := range []Color{s.Dark, s.Light} {
+			found := false
+			for _, brick := range opt.Bricks {
+				found = found || brick.Color == c
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("lego: no %s bricks for the style", c.name)
+			}
+		}
+		styled = color.Palette{s.Dark.color, s.Light.color}
+		if s.Kind == Duotone {
+			src = duotone(src, s.Dark.color, s.Light.color)
+		} else {
+			src = halftone(src, s.Dark.color, s.Light.color)
+		}
+	default:
+		return nil, nil, fmt.Errorf("lego: unknown style %d", s.Kind)
+	}
+	if opt.Lock != nil {
+		for _, c := range opt.Lock.Colors {
+			if styled[styled.Index(c.color)] != c.color {
+				return nil, nil, fmt.Errorf("lego: locked color %s is not used by the style", c.name)
+			}
+		}
+	}
+	return styled, src, nil
+}
+
+// duotone returns src with every pixel replaced by the mix of dark and light
+// that matches its brightness.
+func duotone(src image.Image, dark, light color.Color) image.Image {
+	d := color.NRGBAModel.Convert(dark).(color.NRGBA)
+	l := color.NRGBAModel.Convert(light).(color.NRGBA)
+	mix := func(a, b uint8, f float64) uint8 {
+		return uint8(math.Floor(float64(a)*(1-f) + float64(b)*f + 0.5))
+	}
+	b := src.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			f := brightness(src.At(x, y))
+			out.SetNRGBA(x, y, color.NRGBA{mix(d.R, l.R, f), mix(d.G, l.G, f), mix(d.B, l.B, f), 255})
+		}
+	}
+	return out
+}
+
+// halftone returns a light image with a dark square centered in every cell
+// of HALFTONE_CELL studs, covering as much of the cell as src is dark there.
+func halftone(src image.Image, dark, light color.Color) image.Image {
+	b := src.Bounds()
+	out := image.NewNRGBA(b)
+	n := HALFTONE_CELL
+	if n < 1 {
+		n = 1
+	}
+	for y0 := b.Min.Y; y0 < b.Max.Y; y0 += n {
+		for x0 := b.Min.X; x0 < b.Max.X; x0 += n {
+			cell := image.Rect(x0, y0, x0+n, y0+n).Intersect(b)
+			sum := 0.0
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					sum += brightness(src.At(x, y))
+				}
+			}
+			ink := 1 - sum/float64(cell.Dx()*cell.Dy())
+			side := int(math.Floor(float64(n)*math.Sqrt(ink) + 0.5))
+			min := image.Point{x0 + (n-side)/2, y0 + (n-side)/2}
+			dot := image.Rectangle{min, min.Add(image.Point{side, side})}
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					c := light
+					if (image.Point{x, y}).In(dot) {
+						c = dark
+					}
+					out.Set(x, y, c)
+				}
+			}
+		}
+	}
+	return out
+}
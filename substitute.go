@@ -0,0 +1,86 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// substituteBricks returns bricks with the colors in subs replaced.
+func substituteBricks(bricks []*Brick, subs map[Color]Color) []*Brick {
+	if len(subs) == 0 {
+		return bricks
+	}
+	var result []*Brick
+	seen := make(map[Brick]bool)
+	for _, brick := range bricks {
+		b := *brick
+		if c, ok := subs[b.Color]; ok {
+			b.Color = c
+		}
+		if !seen[b] {
+			seen[b] = true
+			result = append(result, &b)
+		}
+	}
+	return result
+}
+
+// Substitute returns a copy of the panel with the colors in subs replaced,
+// as Options.Substitutions does, building with opt. Bricks of the replaced
+// colors and of their substitutes are laid again, so they merge where they
+// now meet; shaped and Technic bricks are only recolored. The rest of the panel and the
+// overlay are kept.
+func (p *Panel) Substitute(subs map[Color]Color, opt *Options) (*Panel, error) {
+	if err := validateBricks(opt.Bricks); err != nil {
+		return nil, err
+	}
+	o := *opt
+	o.Bricks, o.Substitutions = substituteBricks(opt.Bricks, subs), nil
+	if p.round {
+		o.Bricks = artBricks(o.Bricks)
+	}
+	touched := make(map[Color]bool)
+	for from, to := range subs {
+		touched[from], touched[to] = true, true
+	}
+
+	ret := newPanel(p.bounds)
+	ret.round = p.round
+	used := make(Inventory)
+	colors := make(map[image.Point]Color)
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		b := *brick
+		c, replaced := subs[b.Color]
+		if !replaced {
+			c = b.Color
+		}
+		if touched[b.Color] && b.Mask == 0 && !b.Technic {
+			for y := 0; y < b.Size.Y; y++ {
+				for x := 0; x < b.Size.X; x++ {
+					colors[pos.Add(image.Point{x, y})] = c
+				}
+			}
+			return
+		}
+		b.Color = c
+		ret.bricks.set(pos, &b)
+		used[b.canonical()]++
+	})
+	if len(colors) > 0 {
+		laid, err := placeLayer(p.bounds, colors, &o, false, used)
+		if err != nil {
+			return nil, err
+		}
+		laid.bricks.each(func(pos image.Point, brick *Brick) {
+			ret.bricks.set(pos, brick)
+		})
+	}
+	if p.overlay != nil {
+		ret.overlay = make(map[image.Point]Color, len(p.overlay))
+		for pt, c := range p.overlay {
+			ret.overlay[pt] = c
+		}
+	}
+	p.copyTags(ret, nil)
+	return ret, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// SVGOptions configures ExportSVG.
+type SVGOptions struct {
+	Scale   int  // User units per stud; defaults to 10.
+	Outline bool // Stroke the edges of every brick.
+	Studs   bool // Draw a circle for every stud.
+	Labels  bool // Write the LEGO color ID on every brick.
+}
+
+// errWriter remembers the first write error, so callers can check it once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+func hexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
+
+// contrast returns black or white, whichever reads better over c.
+func contrast(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	if 299*r+587*g+114*b > 500*0xffff {
+		return color.Black
+	}
+	return color.White
+}
+
+// exportShaped writes a brick with a Mask as one square per stud, outlined
+// along the edges of the brick only. It returns where to put its label: the
+// center of its first stud.
+func exportShaped(out *errWriter, min image.Point, brick *Brick, scale int, fill, stroke string) (float64, float64) {
+	var label *image.Point
+	var edges []string
+	for y := 0; y < brick.Size.Y; y++ {
+		for x := 0; x < brick.Size.X; x++ {
+			pt := image.Point{x, y}
+			if !brick.Covers(pt) {
+				continue
+			}
+			cell := min.Add(pt.Mul(scale))
+			if label == nil {
+				label = &cell
+			}
+			out.printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n",
+				cell.X, cell.Y, scale, scale, fill)
+			if !brick.Covers(pt.Add(image.Point{0, -1})) {
+				edges = append(edges, fmt.Sprintf("M%d %dh%d", cell.X, cell.Y, scale))
+			}
+			if !brick.Covers(pt.Add(image.Point{0, 1})) {
+				edges = append(edges, fmt.Sprintf("M%d %dh%d", cell.X, cell.Y+scale, scale))
+			}
+			if !brick.Covers(pt.Add(image.Point{-1, 0})) {
+				edges = append(edges, fmt.Sprintf("M%d %dv%d", cell.X, cell.Y, scale))
+			}
+			if !brick.Covers(pt.Add(image.Point{1, 0})) {
+				edges = append(edges, fmt.Sprintf("M%d %dv%d", cell.X+scale, cell.Y, scale))
+			}
+		}
+	}
+	if stroke != "" {
+		out.printf("<path d=\"%s\" fill=\"none\"%s/>\n", strings.Join(edges, ""), stroke)
+	}
+	if label == nil {
+		return float64(min.X), float64(min.Y)
+	}
+	return float64(label.X) + float64(scale)/2, float64(label.Y) + float64(scale)/2
+}
+
+// ExportSVG writes the panel as an SVG image, with one rectangle per brick.
+// A nil opt uses the defaults.
+func (p *Panel) ExportSVG(w io.Writer, opt *SVGOptions) error {
+	if opt == nil {
+		opt = &SVGOptions{}
+	}
+	scale := opt.Scale
+	if scale <= 0 {
+		scale = 10
+	}
+	size := p.bounds.Size().Mul(scale)
+	out := &errWriter{w: w}
+	out.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.printf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		size.X, size.Y, size.X, size.Y)
+	stroke := ""
+	if opt.Outline {
+		stroke = fmt.Sprintf(" stroke=\"#000000\" stroke-width=\"%g\"", float64(scale)/10)
+	}
+	for _, pos := range p.positions() {
+		brick := p.bricks.at(pos)
+		min := pos.Sub(p.bounds.Min).Sub(brick.anchor()).Mul(scale)
+		dim := brick.Size.Mul(scale)
+		fill := hexColor(brick.Color.color)
+		lx, ly := float64(min.X)+float64(dim.X)/2, float64(min.Y)+float64(dim.Y)/2
+		if brick.Mask == 0 {
+			out.printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"%s/>\n",
+				min.X, min.Y, dim.X, dim.Y, fill, stroke)
+		} else {
+			lx, ly = exportShaped(out, min, brick, scale, fill, stroke)
+		}
+		if opt.Studs {
+			for y := 0; y < brick.Size.Y; y++ {
+				for x := 0; x < brick.Size.X; x++ {
+					if !brick.Covers(image.Point{x, y}) {
+						continue
+					}
+					out.printf("<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"none\" stroke=\"%s\" stroke-opacity=\"0.4\" stroke-width=\"%g\"/>\n",
+						float64(min.X)+(float64(x)+0.5)*float64(scale),
+						float64(min.Y)+(float64(y)+0.5)*float64(scale),
+						0.3*float64(scale), hexColor(contrast(brick.Color.color)), float64(scale)/20)
+				}
+			}
+		}
+		if opt.Labels {
+			out.printf("<text x=\"%g\" y=\"%g\" font-family=\"sans-serif\" font-size=\"%g\" text-anchor=\"middle\" dominant-baseline=\"central\" fill=\"%s\">%d</text>\n",
+				lx, ly,
+				0.4*float64(scale), hexColor(contrast(brick.Color.color)), brick.Color.id)
+		}
+	}
+	for _, pt := range p.overlayPositions() {
+		c := p.overlay[pt]
+		_, _, _, a := c.color.RGBA()
+		rel := pt.Sub(p.bounds.Min)
+		out.printf("<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"%s\" fill-opacity=\"%.2f\"/>\n",
+			(float64(rel.X)+0.5)*float64(scale), (float64(rel.Y)+0.5)*float64(scale),
+			0.45*float64(scale), hexColor(c.color), float64(a)/0xffff)
+	}
+	out.printf("</svg>\n")
+	return out.err
+}
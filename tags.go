@@ -0,0 +1,160 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+var (
+	// FRAME_TAG is the tag of the bricks of Options.Frame.
+	FRAME_TAG = "frame"
+	// MOUNT_TAG is the tag of the Technic bricks of Panel.MountingPlan.
+	MOUNT_TAG = "mount"
+)
+
+// Tag adds tag, such as "background" or "face", to the bricks for which
+// match returns true, to work on groups of bricks: Tagged returns them as a
+// panel of their own, to draw, list with WriteBOM or build step by step.
+// Bricks laid again by Substitute or MountingPlan take the tags of the brick
+// that was at their position.
+func (p *Panel) Tag(tag string, match func(pos image.Point, b Brick) bool) {
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		if match(pos, *brick) {
+			p.addTag(pos, tag)
+		}
+	})
+}
+
+// Untag removes tag from all bricks.
+func (p *Panel) Untag(tag string) {
+	for pos, tags := range p.tags {
+		var kept []string
+		for _, t := range tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		p.setTags(pos, kept)
+	}
+}
+
+// Tags returns the tags of the brick at pos, in order.
+func (p *Panel) Tags(pos image.Point) []string {
+	if p.bricks.at(pos) == nil {
+		return nil
+	}
+	return append([]string(nil), p.tags[pos]...)
+}
+
+// AllTags returns the tags of the bricks of the panel, in order.
+func (p *Panel) AllTags() []string {
+	seen := make(map[string]bool)
+	var result []string
+	p.bricks.each(func(pos image.Point, _ *Brick) {
+		for _, t := range p.tags[pos] {
+			if !seen[t] {
+				seen[t] = true
+				result = append(result, t)
+			}
+		}
+	})
+	sort.Strings(result)
+	return result
+}
+
+// Tagged returns a copy of the panel with only the bricks with any of tags,
+// and the overlay plates over them.
+func (p *Panel) Tagged(tags ...string) *Panel {
+	want := make(map[string]bool)
+	for _, t := range tags {
+		want[t] = true
+	}
+	ret := newPanel(p.bounds)
+	ret.round = p.round
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		for _, t := range p.tags[pos] {
+			if want[t] {
+				b := *brick
+				ret.bricks.set(pos, &b)
+				return
+			}
+		}
+	})
+	p.copyTags(ret, nil)
+	if p.overlay != nil {
+		ret.overlay = make(map[image.Point]Color)
+		cells := ret.cells()
+		for pt, c := range p.overlay {
+			if _, ok := cells.at(pt); ok {
+				ret.overlay[pt] = c
+			}
+		}
+	}
+	return ret
+}
+
+func (p *Panel) addTag(pos image.Point, tag string) {
+	tags := p.tags[pos]
+	i := sort.SearchStrings(tags, tag)
+	if i < len(tags) && tags[i] == tag {
+		return
+	}
+	// Tags may be shared with copies of the panel, so never change them
+	// in place.
+	added := make([]string, 0, len(tags)+1)
+	added = append(append(append(added, tags[:i]...), tag), tags[i:]...)
+	p.setTags(pos, added)
+}
+
+func (p *Panel) setTags(pos image.Point, tags []string) {
+	if len(tags) == 0 {
+		delete(p.tags, pos)
+		return
+	}
+	if p.tags == nil {
+		p.tags = make(map[image.Point][]string)
+	}
+	p.tags[pos] = tags
+}
+
+// copyTags gives the bricks of to the tags of the bricks of p at the
+// positions move maps to theirs, or at the same positions if move is nil.
+func (p *Panel) copyTags(to *Panel, move func(image.Point) image.Point) {
+	for pos, tags := range p.tags {
+		if p.bricks.at(pos) == nil {
+			continue
+		}
+		if move != nil {
+			pos = move(pos)
+		}
+		if to.bricks.at(pos) != nil {
+			to.setTags(pos, tags)
+		}
+	}
+}
+
+// applyTagMasks tags the bricks of p covering studs where the masks of
+// Options.TagMasks are bright, the masks covering the area of the image
+// resized by r.
+func (r *resized) applyTagMasks(p *Panel, masks map[string]image.Image) {
+	if len(masks) == 0 {
+		return
+	}
+	cells := p.cells()
+	min := p.bounds.Min.Add(r.g.offset)
+	for tag, m := range masks {
+		mask := scaleNearest(crop(m, r.g.project(r.bounds, m.Bounds())), r.g.size)
+		for y := 0; y < r.g.size.Y; y++ {
+			for x := 0; x < r.g.size.X; x++ {
+				if color.GrayModel.Convert(mask.At(x, y)).(color.Gray).Y < 128 {
+					continue
+				}
+				if pos, ok := cells.at(image.Point{min.X + x, min.Y + y}); ok {
+					p.addTag(pos, tag)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"golang.org/x/image/font/basicfont"
+	"image"
+	"testing"
+)
+
+// tagAll tags every brick of p with tag.
+func tagAll(p *Panel, tag string) {
+	p.Tag(tag, func(image.Point, Brick) bool { return true })
+}
+
+func TestTagsCleared(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(p *Panel) error
+	}{
+		{"Remove then Place", func(p *Panel) error {
+			if !p.Remove(image.Point{0, 0}) {
+				t.Fatal("no brick at (0, 0)")
+			}
+			return p.Place(image.Point{0, 0}, Brick{image.Point{1, 1}, WHITE, 0, false})
+		}},
+		{"Embed", func(p *Panel) error {
+			sig, err := NewTextPanel("A", basicfont.Face7x13, &Options{Bricks: BASIC_BRICKS})
+			if err != nil {
+				return err
+			}
+			return p.Embed(sig, image.Point{0, 0}, EmbedOpaque)
+		}},
+	} {
+		p, err := NewPanel(gradient(64, 48), &Options{Width: 24, Bricks: ALL_BRICKS})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagAll(p, "face")
+		if err := tc.edit(p); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if tags := p.Tags(image.Point{0, 0}); len(tags) != 0 {
+			t.Errorf("%s: new brick at (0, 0) has tags %v", tc.name, tags)
+		}
+		if p.Tagged("face").bricks.at(image.Point{0, 0}) != nil {
+			t.Errorf("%s: new brick at (0, 0) is tagged", tc.name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// NewTextPanel builds a panel showing text, one pixel of face per stud, in
+// Options.Foreground (default BLACK) over Options.Background (default
+// WHITE). Lines are separated by "\n" and centered. Glyphs such as emoji are
+// drawn with their outline only, in the foreground color.
+//
+// If Width or Height are set, the text is centered in a panel of that size;
+// otherwise the panel fits the text with a one stud margin. Of the other
+// options only Bricks and the ones affecting placement are used.
+func NewTextPanel(text string, face font.Face, opt *Options) (*Panel, error) {
+	fg, bg, m, err := twoColors(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(text, "\n")
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	textSize := image.Point{0, lineHeight * len(lines)}
+	widths := make([]int, len(lines))
+	for i, line := range lines {
+		widths[i] = font.MeasureString(face, line).Ceil()
+		if widths[i] > textSize.X {
+			textSize.X = widths[i]
+		}
+	}
+	size := textSize.Add(image.Point{2, 2})
+	if opt.Width > 0 {
+		size.X = int(opt.Width)
+	}
+	if opt.Height > 0 {
+		size.Y = int(opt.Height)
+	}
+	if textSize.X > size.X || textSize.Y > size.Y {
+		return nil, fmt.Errorf("lego: text needs %dx%d studs, panel is %dx%d",
+			textSize.X, textSize.Y, size.X, size.Y)
+	}
+	if err := checkSize(size, opt); err != nil {
+		return nil, err
+	}
+
+	mask := image.NewAlpha(image.Rectangle{image.ZP, size})
+	d := &font.Drawer{Dst: mask, Src: image.Opaque, Face: face}
+	top := (size.Y - textSize.Y) / 2
+	for i, line := range lines {
+		x := (size.X - widths[i]) / 2
+		baseline := top + i*lineHeight + metrics.Ascent.Ceil()
+		d.Dot = fixed.P(x, baseline)
+		d.DrawString(line)
+	}
+
+	dst := image.NewPaletted(mask.Bounds(), color.Palette{bg.color, fg.color})
+	for i, a := range mask.Pix {
+		if a >= 0x80 {
+			dst.Pix[i] = 1
+		}
+	}
+	p, err := place(context.Background(), dst, m, opt, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.framed(opt)
+}
+
+// twoColors returns the foreground and background colors of opt, with their
+// defaults, and the color mapping for its bricks.
+func twoColors(opt *Options) (fg, bg Color, m map[color.Color]Color, err error) {
+	if err = validateBricks(opt.Bricks); err != nil {
+		return
+	}
+	fg, bg = opt.Foreground, opt.Background
+	if fg.color == nil {
+		fg = BLACK
+	}
+	if bg.color == nil {
+		bg = WHITE
+	}
+	m = make(map[color.Color]Color)
+	for _, brick := range opt.Bricks {
+		m[brick.Color.color] = brick.Color
+	}
+	for _, c := range []Color{fg, bg} {
+		if _, ok := m[c.color]; !ok {
+			err = fmt.Errorf("lego: no %s bricks", c.name)
+			return
+		}
+	}
+	return
+}
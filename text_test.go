@@ -0,0 +1,70 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"golang.org/x/image/font/basicfont"
+	"image"
+	"testing"
+)
+
+func TestNewTextPanel(t *testing.T) {
+	face := basicfont.Face7x13
+	// countFG builds text and returns its size and how many studs are in
+	// the foreground color.
+	countFG := func(text string, opt *Options) (image.Point, int, error) {
+		p, err := NewTextPanel(text, face, opt)
+		if err != nil {
+			return image.ZP, 0, err
+		}
+		fg := opt.Foreground
+		if fg.color == nil {
+			fg = BLACK
+		}
+		n := 0
+		colors := studColors(p)
+		if len(colors) != p.Size().X*p.Size().Y {
+			t.Errorf("%q: %d of %v studs covered", text, len(colors), p.Size())
+		}
+		for _, c := range colors {
+			if c == fg {
+				n++
+			}
+		}
+		return p.Size(), n, nil
+	}
+	size, one, err := countFG("A", &Options{Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != (image.Point{7 + 2, 13 + 2}) || one == 0 {
+		t.Fatalf("A: %v panel with %d dark studs", size, one)
+	}
+	for _, tc := range []struct {
+		text string
+		opt  Options
+		size image.Point
+		fg   int
+	}{
+		{"AA", Options{Bricks: BASIC_BRICKS}, image.Point{2*7 + 2, 13 + 2}, 2 * one},
+		{"A\nA", Options{Bricks: BASIC_BRICKS}, image.Point{7 + 2, 2*13 + 2}, 2 * one},
+		{"A", Options{Width: 20, Height: 20, Bricks: BASIC_BRICKS}, image.Point{20, 20}, one},
+		{"A", Options{Foreground: BRIGHT_RED, Background: BRIGHT_YELLOW, Bricks: BASIC_BRICKS}, size, one},
+	} {
+		size, n, err := countFG(tc.text, &tc.opt)
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		if size != tc.size || n != tc.fg {
+			t.Errorf("%q: %v panel with %d studs in the foreground color, want %v and %d", tc.text, size, n, tc.size, tc.fg)
+		}
+	}
+	for name, opt := range map[string]*Options{
+		"too narrow":          {Width: 5, Bricks: BASIC_BRICKS},
+		"no foreground color": {Foreground: DARK_STONE_GREY, Bricks: BASIC_BRICKS},
+	} {
+		if _, _, err := countFG("A", opt); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
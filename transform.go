@@ -0,0 +1,60 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// transformed returns a copy of the panel, of the given size, with every
+// stud moved by stud, which takes and returns points relative to the
+// top-left corner of the panel, and every brick turned by turn to match.
+func (p *Panel) transformed(size image.Point, stud func(image.Point) image.Point, turn func(Brick) Brick) *Panel {
+	min := p.bounds.Min
+	result := newPanel(image.Rectangle{min, min.Add(size)})
+	result.round = p.round
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		corner := pos.Sub(brick.anchor()).Sub(min)
+		a, b := stud(corner), stud(corner.Add(brick.Size).Sub(image.Point{1, 1}))
+		turned := turn(*brick)
+		at := image.Point{minInt(a.X, b.X), minInt(a.Y, b.Y)}
+		result.bricks.set(at.Add(turned.anchor()).Add(min), &turned)
+		if tags := p.tags[pos]; tags != nil {
+			result.setTags(at.Add(turned.anchor()).Add(min), tags)
+		}
+	})
+	if p.overlay != nil {
+		result.overlay = make(map[image.Point]Color)
+		for pt, c := range p.overlay {
+			result.overlay[stud(pt.Sub(min)).Add(min)] = c
+		}
+	}
+	return result
+}
+
+// Rotate90 returns a copy of the panel turned a quarter clockwise, for
+// mounting it on its side.
+func (p *Panel) Rotate90() *Panel {
+	size := p.bounds.Size()
+	return p.transformed(image.Point{size.Y, size.X}, func(pt image.Point) image.Point {
+		return image.Point{size.Y - 1 - pt.Y, pt.X}
+	}, Brick.rotate)
+}
+
+// FlipH returns a mirror image of the panel, flipped left to right, as seen
+// from behind glass. Shaped bricks are mirrored too.
+func (p *Panel) FlipH() *Panel {
+	size := p.bounds.Size()
+	return p.transformed(size, func(pt image.Point) image.Point {
+		return image.Point{size.X - 1 - pt.X, pt.Y}
+	}, Brick.mirror)
+}
+
+// FlipV returns a copy of the panel flipped top to bottom.
+func (p *Panel) FlipV() *Panel {
+	size := p.bounds.Size()
+	return p.transformed(size, func(pt image.Point) image.Point {
+		return image.Point{pt.X, size.Y - 1 - pt.Y}
+	}, func(b Brick) Brick {
+		return b.rotate().rotate().mirror()
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"testing"
+)
+
+// samePanels returns whether a and b have the same bricks and overlay
+// plates at the same positions.
+func samePanels(a, b *Panel) bool {
+	if a.bounds != b.bounds || a.bricks.len() != b.bricks.len() || len(a.overlay) != len(b.overlay) {
+		return false
+	}
+	same := true
+	a.bricks.each(func(pos image.Point, brick *Brick) {
+		if other := b.bricks.at(pos); other == nil || *other != *brick {
+			same = false
+		}
+	})
+	for pt, c := range a.overlay {
+		if b.overlay[pt] != c {
+			same = false
+		}
+	}
+	return same
+}
+
+func TestTransformsInvert(t *testing.T) {
+	p, err := NewPanel(gradient(100, 50), &Options{
+		Width:    20,
+		Bricks:   EXTENDED_BRICKS,
+		Frame:    FrameSpec{Width: 2, Color: BLACK, Corners: true},
+		Overlays: []Color{TRANS_RED, TRANS_BLUE},
+		Dither:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		f    func(*Panel) *Panel
+		n    int
+	}{
+		{"Rotate90", (*Panel).Rotate90, 4},
+		{"FlipH", (*Panel).FlipH, 2},
+		{"FlipV", (*Panel).FlipV, 2},
+	} {
+		q := p
+		for i := 1; i <= tc.n; i++ {
+			q = tc.f(q)
+			// Every step must still be a valid panel.
+			check := newPanel(q.bounds)
+			q.bricks.each(func(pos image.Point, b *Brick) {
+				if err := check.Place(pos, *b); err != nil {
+					t.Fatalf("%s x%d: %v", tc.name, i, err)
+				}
+			})
+			if i < tc.n && samePanels(p, q) {
+				t.Errorf("%s x%d: panel unchanged", tc.name, i)
+			}
+		}
+		if !samePanels(p, q) {
+			t.Errorf("%s x%d: not the original panel", tc.name, tc.n)
+		}
+	}
+}
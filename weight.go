@@ -0,0 +1,53 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+)
+
+// EstimateWeight returns the approximate mass of the bricks in the panel, in
+// grams, not counting any baseplates.
+func (p *Panel) EstimateWeight() float64 {
+	total := 0.0
+	for brick, n := range p.CountBricks() {
+		if p.round {
+			total += overlayMass * float64(n)
+			continue
+		}
+		total += brick.part().mass * float64(n)
+	}
+	return total + overlayMass*float64(len(p.overlay))
+}
+
+// EstimatePlateWeights splits the panel into baseplates of the given size,
+// starting at its top-left corner, and returns the approximate mass of the
+// bricks on each, in grams, keyed by the plate's column and row. Bricks
+// spanning several plates are shared by stud.
+func (p *Panel) EstimatePlateWeights(plate image.Point) map[image.Point]float64 {
+	result := make(map[image.Point]float64)
+	if plate.X <= 0 || plate.Y <= 0 {
+		return result
+	}
+	index := func(pt image.Point) image.Point {
+		rel := pt.Sub(p.bounds.Min)
+		return image.Point{rel.X / plate.X, rel.Y / plate.Y}
+	}
+	p.bricks.each(func(pos image.Point, brick *Brick) {
+		mass := brick.part().mass
+		if p.round {
+			mass = overlayMass
+		}
+		perStud := mass / float64(brick.Area())
+		for y := 0; y < brick.Size.Y; y++ {
+			for x := 0; x < brick.Size.X; x++ {
+				if brick.Covers(image.Point{x, y}) {
+					result[index(pos.Add(image.Point{x, y}.Sub(brick.anchor())))] += perStud
+				}
+			}
+		}
+	})
+	for pt := range p.overlay {
+		result[index(pt)] += overlayMass
+	}
+	return result
+}
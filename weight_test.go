@@ -0,0 +1,36 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestEstimatePlateWeights(t *testing.T) {
+	square, err := NewPanel(gradient(64, 48), &Options{Width: 32, Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	art, err := NewArtPanel(gradient(64, 64), &Options{Bricks: ALL_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		p     *Panel
+		plate image.Point
+	}{
+		{"bricks", square, image.Point{16, 16}},
+		{"bricks, uneven plates", square, image.Point{10, 7}},
+		{"round plates", art, image.Point{16, 16}},
+	} {
+		sum := 0.0
+		for _, w := range tc.p.EstimatePlateWeights(tc.plate) {
+			sum += w
+		}
+		if want := tc.p.EstimateWeight(); math.Abs(sum-want) > 1e-6 {
+			t.Errorf("%s: plate weights add up to %.2f g, EstimateWeight is %.2f g", tc.name, sum, want)
+		}
+	}
+}
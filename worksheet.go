@@ -0,0 +1,70 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"errors"
+	"image"
+	"io"
+)
+
+// colorRun is a span of studs of the same color along a row. ok is false for
+// studs without a brick.
+type colorRun struct {
+	n     int
+	color Color
+	ok    bool
+}
+
+func (r colorRun) name() string {
+	if !r.ok {
+		return "empty"
+	}
+	return r.color.name
+}
+
+// rowRuns splits the studs of row y from x0 to x1 into runs of the same
+// color.
+func (p *Panel) rowRuns(cells *cellGrid, y, x0, x1 int) []colorRun {
+	var result []colorRun
+	for x := x0; x < x1; x++ {
+		c, ok := colorAt(p, cells, image.Point{x, y})
+		if n := len(result); n > 0 && result[n-1].ok == ok && result[n-1].color == c {
+			result[n-1].n++
+			continue
+		}
+		result = append(result, colorRun{1, c, ok})
+	}
+	return result
+}
+
+// WriteWorksheets writes printable sheets for building the panel row by
+// row: every row, top to bottom, as runs of studs of the same color, such as
+// "Row 1: 7×White (#1), 2×Black (#26), 3×White (#1)". Every sheet holds
+// rowsPerSheet rows and ends with a form feed, so each is printed on its own
+// page.
+func (p *Panel) WriteWorksheets(w io.Writer, rowsPerSheet int) error {
+	if rowsPerSheet <= 0 {
+		return errors.New("lego: rowsPerSheet must be positive")
+	}
+	out := &errWriter{w: w}
+	cells := p.cells()
+	rows := p.bounds.Dy()
+	sheets := (rows + rowsPerSheet - 1) / rowsPerSheet
+	for sheet := 0; sheet < sheets; sheet++ {
+		first := sheet * rowsPerSheet
+		last := minInt(first+rowsPerSheet, rows)
+		out.printf("Sheet %d of %d: rows %d-%d of %d\n\n", sheet+1, sheets, first+1, last, rows)
+		for row := first; row < last; row++ {
+			out.printf("Row %d:", row+1)
+			for i, run := range p.rowRuns(cells, p.bounds.Min.Y+row, p.bounds.Min.X, p.bounds.Max.X) {
+				if i > 0 {
+					out.printf(",")
+				}
+				out.printf(" %d×%s", run.n, run.name())
+			}
+			out.printf("\n")
+		}
+		out.printf("\f")
+	}
+	return out.err
+}
@@ -0,0 +1,32 @@
+// Copyright 2014 Leonardo "Bubble" Mesquita
+package lego
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestWriteWorksheets(t *testing.T) {
+	p, err := NewPanel(blocks(8, 3, image.Rect(2, 1, 5, 3)), &Options{Width: 8, Bricks: BASIC_BRICKS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.WriteWorksheets(&buf, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := "Sheet 1 of 2: rows 1-2 of 3\n\n" +
+		"Row 1: 8×White (#1)\n" +
+		"Row 2: 2×White (#1), 3×Bright red (#21), 3×White (#1)\n" +
+		"\f" +
+		"Sheet 2 of 2: rows 3-3 of 3\n\n" +
+		"Row 3: 2×White (#1), 3×Bright red (#21), 3×White (#1)\n" +
+		"\f"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteWorksheets wrote\n%q\nwant\n%q", got, want)
+	}
+	if err := p.WriteWorksheets(&buf, 0); err == nil {
+		t.Error("wrote sheets of 0 rows")
+	}
+}